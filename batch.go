@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/spf13/pflag"
+)
+
+// Scenario is a single DCA backtest of a batch run.
+type Scenario struct {
+	Symbols   []SymbolSpec
+	From      string
+	To        string
+	Amount    float64
+	Frequency Frequency
+}
+
+// ReadScenariosCSV reads a CSV file of scenarios with a header row naming the
+// columns, in any order:
+//
+//   - symbols: the symbols to DCA into separated by spaces or semicolons,
+//     or by commas if quoted, required
+//   - from: the start date as YYYY-MM-DD, required
+//   - to: the end date, defaults to today
+//   - amount: the amount to invest per purchase, defaults to 500
+//   - frequency: daily, weekly, biweekly, monthly, quarterly or yearly, defaults to monthly
+func ReadScenariosCSV(file string) ([]Scenario, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cr := csv.NewReader(f)
+	cr.TrimLeadingSpace = true
+	cr.Comment = '#'
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not read scenarios file %s: %w", file, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("scenarios file %s is empty", file)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"symbols", "from"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("scenarios file %s is missing a '%s' column", file, required)
+		}
+	}
+
+	value := func(rec []string, column, def string) string {
+		i, ok := columns[column]
+		if !ok || strings.TrimSpace(rec[i]) == "" {
+			return def
+		}
+		return strings.TrimSpace(rec[i])
+	}
+
+	var scenarios []Scenario
+
+	for line, rec := range records[1:] {
+		symbols := strings.FieldsFunc(value(rec, "symbols", ""), func(r rune) bool {
+			return r == ' ' || r == ';' || r == ','
+		})
+		specs, err := ParseSymbolSpecs(symbols)
+		if err != nil {
+			return nil, fmt.Errorf("scenarios file %s line %d: %w", file, line+2, err)
+		}
+		if len(specs) == 0 {
+			return nil, fmt.Errorf("scenarios file %s line %d: no symbols", file, line+2)
+		}
+
+		amount, err := strconv.ParseFloat(value(rec, "amount", "500"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("scenarios file %s line %d: invalid amount '%s'", file, line+2, value(rec, "amount", ""))
+		}
+
+		f, err := ParseFrequency(value(rec, "frequency", "monthly"))
+		if err != nil {
+			return nil, fmt.Errorf("scenarios file %s line %d: %w", file, line+2, err)
+		}
+
+		scenarios = append(scenarios, Scenario{
+			Symbols:   specs,
+			From:      value(rec, "from", ""),
+			To:        value(rec, "to", Today()),
+			Amount:    amount,
+			Frequency: f,
+		})
+	}
+
+	return scenarios, nil
+}
+
+// RunBatch backtests each scenario with the same options, up to parallel of
+// them at a time, sharing the data source and its cache between them, and
+// writes one CSV row of results per scenario to w, in the scenarios' order.
+func RunBatch(scenarios []Scenario, opts Options, parallel int, w io.Writer) error {
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	portfolios := make([]*DCAPortfolio, len(scenarios))
+	panics := make([]interface{}, len(scenarios))
+	running := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+
+	for i, sc := range scenarios {
+		wg.Add(1)
+		go func(i int, sc Scenario) {
+			defer wg.Done()
+			running <- struct{}{}
+			defer func() { <-running }()
+			defer func() { panics[i] = recover() }()
+
+			portfolios[i] = NewDCAPortfolio(sc.Symbols, sc.From, sc.To, sc.Frequency, sc.Amount, opts)
+		}(i, sc)
+	}
+	wg.Wait()
+
+	// Failing like the first scenario failing would when run one by one
+	for _, p := range panics {
+		if p != nil {
+			panic(p)
+		}
+	}
+
+	cw := csv.NewWriter(w)
+
+	cw.Write([]string{"symbols", "from", "to", "amount", "frequency", "total_invested", "total_return", "pnl", "cagr", "volatility"})
+
+	for i, sc := range scenarios {
+		dp := portfolios[i]
+
+		cw.Write([]string{
+			strings.Join(dp.Symbols(), " "),
+			dp.From.Format("2006-01-02"),
+			dp.To.Format("2006-01-02"),
+			strconv.FormatFloat(sc.Amount, 'f', -1, 64),
+			sc.Frequency.String(),
+			strconv.FormatFloat(dp.TotalInvested, 'f', 2, 64),
+			strconv.FormatFloat(dp.TotalReturn, 'f', 2, 64),
+			strconv.FormatFloat(dp.PNL, 'f', 2, 64),
+			strconv.FormatFloat(dp.CAGR, 'f', 2, 64),
+			strconv.FormatFloat(dp.Volatility, 'f', 2, 64),
+		})
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// batchMain runs the batch subcommand: nasdaq batch [flags] <scenarios.csv>
+func batchMain(args []string) {
+	fs := pflag.NewFlagSet("batch", pflag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s batch [flags] <scenarios.csv>\n\nBacktests every scenario in the CSV file and prints a CSV of the results.\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	dataDir := fs.String("data-dir", "", "Read trading data from <SYMBOL>.csv files in this directory instead of the NASDAQ API")
+	memoryCacheSize := fs.Int("memory-cache-size", 64, "Number of fetched responses to keep in memory")
+	failOnEmpty := fs.Bool("fail-on-empty", true, "Fail when the NASDAQ API returns no trading data for a symbol")
+	parallel := fs.Int("parallel", 4, "Number of scenarios to backtest at a time, fetches of the same data are shared")
+	outputFile := fs.StringP("output", "O", "", "Write the results CSV to this file instead of stdout, creating its directory if needed")
+
+	err := fs.Parse(args)
+	if err == pflag.ErrHelp {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		fs.Usage()
+		exit(ExitUsage)
+	}
+
+	handleInterrupts()
+	if fs.NArg() != 1 {
+		fs.Usage()
+		exit(ExitUsage)
+	}
+
+	scenarios, err := ReadScenariosCSV(fs.Arg(0))
+	if err != nil {
+		panic(err)
+	}
+
+	opts := Options{
+		Source: NASDAQSource{
+			Cache:             NewLRUCache(*memoryCacheSize, &FileCache{Dir: "."}),
+			FailOnEmpty:       *failOnEmpty,
+			RefreshIncomplete: true,
+		},
+	}
+	if *dataDir != "" {
+		opts.Source = &CSVDirSource{Dir: *dataDir}
+	}
+
+	var w io.Writer = os.Stdout
+	if *outputFile != "" {
+		f, err := CreateOutputFile(*outputFile)
+		if err != nil {
+			panic(err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	err = RunBatch(scenarios, opts, *parallel, w)
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunBatchWritesARowPerScenario(t *testing.T) {
+	scenarios, err := ReadScenariosCSV(filepath.Join("testdata", "scenarios.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scenarios) != 3 {
+		t.Fatalf("%d scenarios read, want 3", len(scenarios))
+	}
+
+	var buf bytes.Buffer
+	err = RunBatch(scenarios, Options{Source: &CSVDirSource{Dir: filepath.Join("testdata", "prices")}}, 2, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("%d CSV records, want a header and 3 rows", len(records))
+	}
+	for i, want := range []string{"AAPL", "AAPL MSFT", "MSFT"} {
+		if got := records[i+1][0]; got != want {
+			t.Errorf("row %d is for %s, want %s", i+1, got, want)
+		}
+	}
+}
+
+func TestReadScenariosCSVDefaults(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "scenarios.csv")
+	writeFile(t, file, "from,symbols\n2020-01-01,aapl;msft\n")
+
+	scenarios, err := ReadScenariosCSV(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sc := scenarios[0]
+	if len(sc.Symbols) != 2 || sc.Symbols[0].Symbol != "AAPL" || sc.Symbols[1].Symbol != "MSFT" {
+		t.Errorf("symbols %v, want AAPL and MSFT", sc.Symbols)
+	}
+	if sc.To != Today() || sc.Amount != 500 || sc.Frequency != Monthly {
+		t.Errorf("to %s investing %v %s, want the defaults", sc.To, sc.Amount, sc.Frequency)
+	}
+
+	writeFile(t, file, "symbols,to\nAAPL,2020-12-31\n")
+	if _, err := ReadScenariosCSV(file); err == nil {
+		t.Error("no error without a from column")
+	}
+}
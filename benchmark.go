@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// BenchmarkBlend is a benchmark made up of several symbols, each invested
+// into with its share of the contributions.
+type BenchmarkBlend struct {
+	Name    string // e.g. "60% SPY, 40% AGG"
+	Symbols []string
+	Weights []float64 // Summing up to 1
+}
+
+// ParseBenchmarkBlend parses symbols with percentages separated by commas,
+// e.g. "60% SPY, 40% AGG". The percentages must sum up to 100.
+func ParseBenchmarkBlend(s string) (*BenchmarkBlend, error) {
+	bb := new(BenchmarkBlend)
+	seen := make(map[string]bool)
+	var names []string
+	var sum float64
+
+	for _, part := range strings.Split(s, ",") {
+		fields := strings.Fields(part)
+		if len(fields) != 2 || !strings.HasSuffix(fields[0], "%") {
+			return nil, fmt.Errorf("benchmark '%s': expected a percentage and a symbol like '60%% SPY', got '%s'", s, strings.TrimSpace(part))
+		}
+
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(fields[0], "%"), 64)
+		if err != nil || percent <= 0 {
+			return nil, fmt.Errorf("benchmark '%s': invalid percentage '%s'", s, fields[0])
+		}
+		symbol := strings.ToUpper(fields[1])
+		if seen[symbol] {
+			return nil, fmt.Errorf("benchmark '%s': %s is given more than once", s, symbol)
+		}
+		seen[symbol] = true
+
+		bb.Symbols = append(bb.Symbols, symbol)
+		bb.Weights = append(bb.Weights, percent/100)
+		names = append(names, fields[0]+" "+symbol)
+		sum += percent
+	}
+	if math.Abs(sum-100) > weightsTolerance*100 {
+		return nil, fmt.Errorf("benchmark '%s': percentages sum up to %g instead of 100", s, sum)
+	}
+	bb.Name = strings.Join(names, ", ")
+
+	return bb, nil
+}
+
+// newBlendedBenchmark invests the contributions into the symbols of the blend
+// as a portfolio of its own.
+func newBlendedBenchmark(bb *BenchmarkBlend, fromDate, toDate string, f Frequency, spend float64, opts Options) *DCAPortfolio {
+	opts.Benchmark = ""
+	opts.BenchmarkBlend = nil
+	opts.Holdings = nil
+	opts.ExpenseRatio = 0
+	opts.RebalanceThreshold = 0
+	opts.Weighting = CustomWeighting
+	opts.CustomWeights = bb.Weights
+	opts.NormalizeWeights = false
+
+	var specs []SymbolSpec
+	for _, symbol := range bb.Symbols {
+		specs = append(specs, SymbolSpec{Symbol: symbol})
+	}
+
+	bp := NewDCAPortfolio(specs, fromDate, toDate, f, spend, opts)
+	bp.Name = bb.Name
+
+	return bp
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writePriceCSV writes rows as a price CSV file with date and price columns.
+func writePriceCSV(t *testing.T, file string, rows []*TradingData) {
+	t.Helper()
+	var sb strings.Builder
+	sb.WriteString("date,price\n")
+	for _, r := range RowsAscending(rows) {
+		fmt.Fprintf(&sb, "%s,%s\n", NASDAQDateToTime(r.Date).Format("2006-01-02"), strings.TrimPrefix(r.Close, "$"))
+	}
+	writeFile(t, file, sb.String())
+}
+
+func TestBenchmarkFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "BENCH.csv")
+	writePriceCSV(t, file, weekdayRows("2020-01-01", "2020-12-31", flatPrice(50)))
+	cs := &CSVFileSource{File: file}
+
+	source := testSource{"X": weekdayRows("2020-01-01", "2020-12-31", func(t time.Time) float64 {
+		return 10 + 10*float64(t.YearDay())/366
+	})}
+	opts := Options{Source: source, Benchmark: cs.Symbol(), BenchmarkSource: cs}
+
+	dp := NewDCAPortfolio([]SymbolSpec{{Symbol: "X"}}, "2020-01-01", "2020-12-31", Monthly, 100, opts)
+	if dp.Benchmark == nil {
+		t.Fatal("no benchmark")
+	}
+	if dp.Benchmark.TotalInvested != dp.TotalInvested || dp.Benchmark.PNL != 0 {
+		t.Errorf("benchmark invested %v with PNL %v, want %v with 0", dp.Benchmark.TotalInvested, dp.Benchmark.PNL, dp.TotalInvested)
+	}
+
+	got := captureOutput(t, dp.PrintBenchmark)
+	for _, want := range []string{
+		"Benchmark      : BENCH\n",
+		"Total Invested : $1,200.00\n",
+		"PNL            : 0.00 %\n",
+		fmt.Sprintf("vs Portfolio   : %+.02f %%\n", dp.PNL),
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("no %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestBenchmarkDrawdownCompare(t *testing.T) {
+	defer func(compare bool) { compareDrawdowns = compare }(compareDrawdowns)
+	// Down 10% and 40% in the middle of the year, recovering by the end
+	dip := func(depth float64) func(time.Time) float64 {
+		return func(t time.Time) float64 {
+			if t.Month() >= 5 && t.Month() <= 8 {
+				return 100 * (1 - depth)
+			}
+			return 100
+		}
+	}
+	source := testSource{
+		"X":     weekdayRows("2020-01-01", "2020-12-31", dip(0.1)),
+		"BENCH": weekdayRows("2020-01-01", "2020-12-31", dip(0.4)),
+	}
+	dp := NewDCAPortfolio([]SymbolSpec{{Symbol: "X"}}, "2020-01-01", "2020-12-31", Monthly, 100, Options{Source: source, Benchmark: "BENCH"})
+
+	pdd, bdd := MaxDrawdown(dp.DailyValueSeries()), MaxDrawdown(dp.Benchmark.DailyValueSeries())
+	if pdd <= 0 || bdd <= pdd {
+		t.Fatalf("max drawdowns %.2f %% and %.2f %% for the benchmark, want the benchmark's deeper", pdd, bdd)
+	}
+
+	compareDrawdowns = true
+	want := printer.Sprintf("Max Drawdown   : %.02f %% vs %.02f %% for the portfolio (%+.02f %%)\n", bdd, pdd, pdd-bdd)
+	if got := captureOutput(t, dp.PrintBenchmark); !strings.Contains(got, want) {
+		t.Errorf("no %q in:\n%s", want, got)
+	}
+
+	compareDrawdowns = false
+	if got := captureOutput(t, dp.PrintBenchmark); strings.Contains(got, "Max Drawdown") {
+		t.Errorf("drawdowns compared without the flag:\n%s", got)
+	}
+}
+
+func TestBlendedBenchmark(t *testing.T) {
+	bb, err := ParseBenchmarkBlend("60% spy, 40% AGG")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// SPY stays flat while AGG gains 50% in July
+	source := testSource{
+		"X":   weekdayRows("2020-01-01", "2020-12-31", flatPrice(10)),
+		"SPY": weekdayRows("2020-01-01", "2020-12-31", flatPrice(100)),
+		"AGG": weekdayRows("2020-01-01", "2020-12-31", func(t time.Time) float64 {
+			if t.Month() < 7 {
+				return 100
+			}
+			return 150
+		}),
+	}
+	opts := Options{Source: source, Benchmark: bb.Name, BenchmarkBlend: bb, AsOf: ISODateToTime("2020-12-31")}
+
+	dp := NewDCAPortfolio([]SymbolSpec{{Symbol: "X"}}, "2020-01-01", "2020-12-31", Yearly, 1000, opts)
+
+	bp := dp.BlendedBenchmark
+	if bp == nil {
+		t.Fatal("no blended benchmark")
+	}
+	// 600 in SPY still worth 600, 400 in AGG worth 600
+	if bp.TotalInvested != 1000 || !approx(bp.TotalReturn, 1200) || !approx(SimplePNLOf(bp.TotalInvested, bp.TotalReturn), 20) {
+		t.Errorf("benchmark invested %v returning %v, want 1000 returning 1200", bp.TotalInvested, bp.TotalReturn)
+	}
+	if got := captureOutput(t, dp.PrintBenchmark); !strings.Contains(got, "Benchmark      : 60% SPY, 40% AGG\n") {
+		t.Errorf("printed:\n%s", got)
+	}
+
+	for _, s := range []string{"60% SPY, 30% AGG", "SPY", "60% SPY, 40% SPY"} {
+		if _, err := ParseBenchmarkBlend(s); err == nil {
+			t.Errorf("no error for benchmark %q", s)
+		}
+	}
+}
+
+func TestBenchmarkContributionsMatchALateInception(t *testing.T) {
+	source := testSource{
+		"X": weekdayRows("2020-01-01", "2020-06-30", flatPrice(10)),
+		"L": weekdayRows("2020-04-01", "2020-06-30", flatPrice(20)),
+		"B": weekdayRows("2020-01-01", "2020-06-30", flatPrice(50)),
+	}
+	specs := []SymbolSpec{{Symbol: "X"}, {Symbol: "L"}}
+
+	full := NewDCAPortfolio(specs, "2020-01-01", "2020-06-30", Monthly, 200, Options{Source: source, Benchmark: "B"})
+	matched := NewDCAPortfolio(specs, "2020-01-01", "2020-06-30", Monthly, 200, Options{Source: source, Benchmark: "B", BenchmarkContributionsMatch: true})
+
+	if full.Benchmark.TotalInvested <= full.TotalInvested {
+		t.Errorf("benchmark invested %v, want the full spend above the %v invested before L's inception", full.Benchmark.TotalInvested, full.TotalInvested)
+	}
+
+	var want []string
+	for _, c := range matched.contributions() {
+		want = append(want, fmt.Sprintf("%s %.2f", c.Date.Format("2006-01-02"), c.Amount))
+	}
+	var got []string
+	for _, tr := range matched.Benchmark.Transactions {
+		got = append(got, fmt.Sprintf("%s %.2f", tr.Date.Format("2006-01-02"), tr.Amount))
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("benchmark purchases:\n%s\nwant the portfolio's contributions:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if !approx(matched.Benchmark.TotalInvested, matched.TotalInvested) {
+		t.Errorf("benchmark invested %v, want the %v the portfolio did", matched.Benchmark.TotalInvested, matched.TotalInvested)
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Cache stores historical NASDAQ API responses so they don't have to be
+// fetched again.
+type Cache interface {
+	// Get returns the cached response for key, or false if there's none.
+	Get(key string) (*NASDAQHistoricalAPIResponse, bool, error)
+	Set(key string, ndr *NASDAQHistoricalAPIResponse) error
+}
+
+// Locker is implemented by caches shared with other processes, so that a
+// response missing from the cache is only fetched by one of them.
+type Locker interface {
+	// Lock blocks until key is locked, returning the function unlocking it.
+	Lock(key string) (unlock func(), err error)
+}
+
+func CacheKey(ticker, fromDate, toDate string) string {
+	return fmt.Sprintf("%s-%s-%s", ticker, fromDate, toDate)
+}
+
+// FileCache stores each response as a <key>.json file in Dir. With
+// PersistRaw set the response as it was received from the API is also kept
+// in a <key>.raw.json file next to it, with any fields that aren't parsed.
+// Responses with older rows fetched in further requests, when NASDAQ capped
+// the first one, keep those in <key>.raw.2.json, <key>.raw.3.json and so on.
+type FileCache struct {
+	Dir        string
+	PersistRaw bool
+}
+
+func (fc *FileCache) file(key string) string {
+	return filepath.Join(fc.Dir, key+".json")
+}
+
+// rawFile returns the file the page'th response of key is kept in, counting
+// from 0.
+func (fc *FileCache) rawFile(key string, page int) string {
+	if page == 0 {
+		return filepath.Join(fc.Dir, key+".raw.json")
+	}
+	return filepath.Join(fc.Dir, fmt.Sprintf("%s.raw.%d.json", key, page+1))
+}
+
+// Lock locks key with a lock file next to its cache file, <key>.json.lock,
+// that's left in place. Locks aren't supported on all platforms, where it
+// doesn't block.
+func (fc *FileCache) Lock(key string) (func(), error) {
+	return lockFile(fc.file(key) + ".lock")
+}
+
+func (fc *FileCache) Get(key string) (*NASDAQHistoricalAPIResponse, bool, error) {
+	data, err := os.ReadFile(fc.file(key))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	ndr := new(NASDAQHistoricalAPIResponse)
+	err = json.Unmarshal(data, ndr)
+	if err != nil {
+		return nil, false, fmt.Errorf("could not parse cache file %s: %w", fc.file(key), err)
+	}
+
+	return ndr, true, nil
+}
+
+func (fc *FileCache) Set(key string, ndr *NASDAQHistoricalAPIResponse) error {
+	j, err := json.MarshalIndent(ndr, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fileWrites.Lock()
+	defer fileWrites.Unlock()
+
+	if fc.PersistRaw && ndr.raw != nil {
+		for page, data := range ndr.raw {
+			err = writeFileAtomic(fc.rawFile(key, page), data, 0644)
+			if err != nil {
+				return err
+			}
+		}
+		// Pages left over from a response fetched in more requests before
+		for page := len(ndr.raw); ; page++ {
+			if os.Remove(fc.rawFile(key, page)) != nil {
+				break
+			}
+		}
+	}
+
+	return writeFileAtomic(fc.file(key), j, 0644)
+}
+
+// writeFileAtomic writes data to a temporary file next to file and renames it
+// into place, so that file is either left as it was or completely written,
+// even if writing it is interrupted.
+func writeFileAtomic(file string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(tmp.Name(), perm)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), file)
+}
+
+// LRUCache keeps up to Size parsed responses in memory, evicting the least
+// recently used one when full. Misses fall through to Next, if set, so it can
+// be layered above a FileCache to avoid re-reading and re-parsing files.
+// Responses returned from it are shared and must not be modified.
+type LRUCache struct {
+	Size int
+	Next Cache
+
+	mu    sync.Mutex
+	order *list.List
+	items map[string]*list.Element
+}
+
+type lruEntry struct {
+	key string
+	ndr *NASDAQHistoricalAPIResponse
+}
+
+func NewLRUCache(size int, next Cache) *LRUCache {
+	return &LRUCache{
+		Size:  size,
+		Next:  next,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+func (lc *LRUCache) Get(key string) (*NASDAQHistoricalAPIResponse, bool, error) {
+	lc.mu.Lock()
+	if e, ok := lc.items[key]; ok {
+		lc.order.MoveToFront(e)
+		lc.mu.Unlock()
+		return e.Value.(*lruEntry).ndr, true, nil
+	}
+	lc.mu.Unlock()
+
+	if lc.Next == nil {
+		return nil, false, nil
+	}
+
+	ndr, ok, err := lc.Next.Get(key)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+
+	lc.add(key, ndr)
+
+	return ndr, true, nil
+}
+
+// sharedCache returns the cache behind c shared with other processes, past
+// the responses LRUCaches keep in memory, so that what other processes wrote
+// to it since is seen.
+func sharedCache(c Cache) Cache {
+	for {
+		lc, ok := c.(*LRUCache)
+		if !ok || lc.Next == nil {
+			return c
+		}
+		c = lc.Next
+	}
+}
+
+// Lock locks key in Next if it's a Locker.
+func (lc *LRUCache) Lock(key string) (func(), error) {
+	if l, ok := lc.Next.(Locker); ok {
+		return l.Lock(key)
+	}
+	return func() {}, nil
+}
+
+func (lc *LRUCache) Set(key string, ndr *NASDAQHistoricalAPIResponse) error {
+	if lc.Next != nil {
+		err := lc.Next.Set(key, ndr)
+		if err != nil {
+			return err
+		}
+	}
+
+	lc.add(key, ndr)
+
+	return nil
+}
+
+func (lc *LRUCache) add(key string, ndr *NASDAQHistoricalAPIResponse) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	if lc.Size <= 0 {
+		return
+	}
+
+	if e, ok := lc.items[key]; ok {
+		e.Value.(*lruEntry).ndr = ndr
+		lc.order.MoveToFront(e)
+		return
+	}
+
+	lc.items[key] = lc.order.PushFront(&lruEntry{key: key, ndr: ndr})
+
+	for lc.order.Len() > lc.Size {
+		oldest := lc.order.Back()
+		lc.order.Remove(oldest)
+		delete(lc.items, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// WriteOnlyCache never returns cached responses but still stores them in the
+// wrapped Cache, forcing fresh fetches while keeping the cache up to date.
+type WriteOnlyCache struct {
+	Cache
+}
+
+func (WriteOnlyCache) Get(key string) (*NASDAQHistoricalAPIResponse, bool, error) {
+	return nil, false, nil
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingCache counts the lookups of the Cache it wraps.
+type countingCache struct {
+	Cache
+	gets int32
+}
+
+func (cc *countingCache) Get(key string) (*NASDAQHistoricalAPIResponse, bool, error) {
+	atomic.AddInt32(&cc.gets, 1)
+	return cc.Cache.Get(key)
+}
+
+// serveCountedNASDAQ serves a month of trading data for any symbol and
+// returns the number of requests made so far.
+func serveCountedNASDAQ(t *testing.T) func() int32 {
+	var requests int32
+	serveNASDAQ(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		writeNASDAQResponse(w, r.URL.Path[1:], weekdayRows("2020-01-01", "2020-01-31", flatPrice(10)))
+	})
+	return func() int32 { return atomic.LoadInt32(&requests) }
+}
+
+func TestCacheMissThenHit(t *testing.T) {
+	requests := serveCountedNASDAQ(t)
+	cache := mapCache{}
+
+	first := GetNASDAQHistoricialDataCached(cache, "X", "2020-01-01", "2020-01-31", false, false)
+	if requests() != 1 {
+		t.Fatalf("%d requests on a miss, want 1", requests())
+	}
+	if _, ok := cache[historicalCacheKey("X", "2020-01-01", "2020-01-31")]; !ok {
+		t.Fatal("not cached after the miss")
+	}
+
+	second := GetNASDAQHistoricialDataCached(cache, "X", "2020-01-01", "2020-01-31", false, false)
+	if requests() != 1 {
+		t.Errorf("%d requests on a hit, want none after the first", requests())
+	}
+	if len(second.Data.TradesTable.Rows) != len(first.Data.TradesTable.Rows) {
+		t.Errorf("%d rows on a hit, want the %d fetched", len(second.Data.TradesTable.Rows), len(first.Data.TradesTable.Rows))
+	}
+
+	if entries, _ := os.ReadDir("."); len(entries) != 0 {
+		t.Errorf("%d files written, want none with an in-memory cache", len(entries))
+	}
+}
+
+func TestLRUCacheHitsStayInMemory(t *testing.T) {
+	ndr, _ := testSource{"X": weekdayRows("2020-01-01", "2020-01-31", flatPrice(10))}.HistoricalData("X", "2020-01-01", "2020-01-31")
+	fc := &FileCache{Dir: t.TempDir()}
+	err := fc.Set("X", ndr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := &countingCache{Cache: fc}
+	lc := NewLRUCache(2, files)
+
+	for i := 0; i < 3; i++ {
+		got, ok, err := lc.Get("X")
+		if err != nil || !ok || len(got.Data.TradesTable.Rows) != len(ndr.Data.TradesTable.Rows) {
+			t.Fatalf("lookup %d: %v, %v", i+1, ok, err)
+		}
+	}
+	if files.gets != 1 {
+		t.Errorf("%d file cache lookups, want only the first", files.gets)
+	}
+
+	// Evicted as the least recently used, read from the file again
+	lc.Set("Y", ndr)
+	lc.Set("Z", ndr)
+	lc.Get("X")
+	if files.gets != 2 {
+		t.Errorf("%d file cache lookups after evicting X, want 2", files.gets)
+	}
+}
+
+func TestWriteOnlyCacheAlwaysFetches(t *testing.T) {
+	requests := serveCountedNASDAQ(t)
+	fc := &FileCache{Dir: "."}
+	stale, _ := testSource{"X": weekdayRows("2020-01-02", "2020-01-02", flatPrice(1))}.HistoricalData("X", "2020-01-01", "2020-01-31")
+	key := historicalCacheKey("X", "2020-01-01", "2020-01-31")
+	err := fc.Set(key, stale)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ndr := GetNASDAQHistoricialDataCached(WriteOnlyCache{fc}, "X", "2020-01-01", "2020-01-31", false, false)
+
+	if requests() != 1 {
+		t.Errorf("%d requests with a cache file, want 1", requests())
+	}
+	if len(ndr.Data.TradesTable.Rows) == 1 {
+		t.Error("returned the cached response")
+	}
+	cached, _, err := fc.Get(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cached.Data.TradesTable.Rows) != len(ndr.Data.TradesTable.Rows) {
+		t.Errorf("%d rows in the cache file, want the %d fetched", len(cached.Data.TradesTable.Rows), len(ndr.Data.TradesTable.Rows))
+	}
+}
+
+func TestNotModifiedReusesTheCache(t *testing.T) {
+	var requests, notModified int32
+	serveNASDAQ(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.Header.Get("if-none-match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("etag", `"v1"`)
+		writeNASDAQResponse(w, "X", weekdayRows("2020-01-01", "2020-01-31", flatPrice(10)))
+	})
+	cache := mapCache{}
+
+	first := GetNASDAQHistoricialDataCached(cache, "X", "2020-01-01", "2020-01-31", true, false)
+	if first.ETag != `"v1"` {
+		t.Fatalf("ETag %q cached, want \"v1\"", first.ETag)
+	}
+	fetchedAt := first.FetchedAt
+
+	second := GetNASDAQHistoricialDataCached(cache, "X", "2020-01-01", "2020-01-31", true, false)
+	if requests != 2 || notModified != 1 {
+		t.Errorf("%d requests with %d not modified, want 2 with the second not modified", requests, notModified)
+	}
+	if len(second.Data.TradesTable.Rows) != len(first.Data.TradesTable.Rows) {
+		t.Errorf("%d rows when not modified, want the %d cached", len(second.Data.TradesTable.Rows), len(first.Data.TradesTable.Rows))
+	}
+	// Recorded as fetched again without touching the cached response
+	if second == first || !first.FetchedAt.Equal(fetchedAt) || second.FetchedAt.Before(fetchedAt) {
+		t.Errorf("fetched at %s when not modified, with the cached response changed to %s from %s", second.FetchedAt, first.FetchedAt, fetchedAt)
+	}
+}
+
+func TestInterruptedWriteKeepsTheCacheFile(t *testing.T) {
+	fc := &FileCache{Dir: t.TempDir()}
+	old, _ := testSource{"X": weekdayRows("2020-01-01", "2020-01-31", flatPrice(10))}.HistoricalData("X", "2020-01-01", "2020-01-31")
+	err := fc.Set("X", old)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Killed half way through writing a newer response, only its temporary
+	// file is written to
+	data, err := os.ReadFile(fc.file("X"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, fc.file("X")+".123.tmp", string(data[:len(data)/2]))
+
+	got, ok, err := fc.Get("X")
+	if err != nil || !ok || len(got.Data.TradesTable.Rows) != len(old.Data.TradesTable.Rows) {
+		t.Fatalf("%v, %v reading the cache file after the interrupted write", ok, err)
+	}
+
+	err = writeFileAtomic(fc.file("Y"), []byte("{}"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmps, _ := filepath.Glob(filepath.Join(fc.Dir, "Y*.tmp"))
+	if len(tmps) != 0 {
+		t.Errorf("temporary files %v left behind by a completed write", tmps)
+	}
+	if info, err := os.Stat(fc.file("Y")); err != nil || info.Mode().Perm() != 0644 {
+		t.Errorf("written file %v, %v, want mode 0644", info, err)
+	}
+
+	// Writes failing before the rename are reported
+	err = writeFileAtomic(filepath.Join(fc.Dir, "missing", "X.json"), []byte("{}"), 0644)
+	if err == nil {
+		t.Error("no error writing to a missing directory")
+	}
+}
+
+func TestRefreshCacheEndingToday(t *testing.T) {
+	defer func(now func() time.Time) { Now = now }(Now)
+	Now = func() time.Time { return ISODateToTime("2020-01-31").Add(10 * time.Hour) }
+
+	requests := serveCountedNASDAQ(t)
+	stale, _ := testSource{"X": weekdayRows("2020-01-01", "2020-01-30", flatPrice(10))}.HistoricalData("X", "2020-01-01", "2020-01-31")
+	stale.FetchedAt = ISODateToTime("2020-01-31").Add(9 * time.Hour)
+	cache := mapCache{historicalCacheKey("X", "2020-01-01", "2020-01-31"): stale}
+
+	GetNASDAQHistoricialDataCached(cache, "X", "2020-01-01", "2020-01-31", false, false)
+	if requests() != 0 {
+		t.Fatalf("%d requests without refreshing, want the cache used", requests())
+	}
+
+	ndr := GetNASDAQHistoricialDataCached(cache, "X", "2020-01-01", "2020-01-31", false, true)
+	if requests() != 1 {
+		t.Errorf("%d requests for a cache ending today, want it refreshed", requests())
+	}
+	if len(ndr.Data.TradesTable.Rows) <= len(stale.Data.TradesTable.Rows) {
+		t.Errorf("%d rows after the refresh, want today's too", len(ndr.Data.TradesTable.Rows))
+	}
+
+	// Fetched after the day it ends was over
+	done, _ := testSource{"X": weekdayRows("2020-01-01", "2020-01-10", flatPrice(10))}.HistoricalData("X", "2020-01-01", "2020-01-10")
+	done.FetchedAt = ISODateToTime("2020-01-11")
+	cache[historicalCacheKey("X", "2020-01-01", "2020-01-10")] = done
+	GetNASDAQHistoricialDataCached(cache, "X", "2020-01-01", "2020-01-10", false, true)
+	if requests() != 1 {
+		t.Errorf("%d requests, want a complete cache used", requests())
+	}
+}
+
+func TestLockedFileCacheFetchesOnceConcurrently(t *testing.T) {
+	var requests int32
+	serveNASDAQ(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		time.Sleep(50 * time.Millisecond)
+		writeNASDAQResponse(w, "X", weekdayRows("2020-01-01", "2020-01-31", flatPrice(10)))
+	})
+
+	// A cache of its own for every fetch, like separate processes sharing
+	// the directory, coalesced by the lock file alone
+	const n = 8
+	var wg sync.WaitGroup
+	rows := make([]int, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ndr := GetNASDAQHistoricialDataCached(&FileCache{Dir: "."}, "X", "2020-01-01", "2020-01-31", false, false)
+			rows[i] = len(ndr.Data.TradesTable.Rows)
+		}(i)
+	}
+	wg.Wait()
+
+	if requests != 1 {
+		t.Errorf("%d requests for %d concurrent fetches, want one", requests, n)
+	}
+	for i, r := range rows {
+		if r != 23 {
+			t.Errorf("fetch %d got %d rows, want 23", i, r)
+		}
+	}
+}
+
+func TestLockedLRUCachesRefreshAStaleFileOnce(t *testing.T) {
+	var requests int32
+	serveNASDAQ(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		time.Sleep(50 * time.Millisecond)
+		writeNASDAQResponse(w, "X", weekdayRows("2020-01-01", "2020-01-31", flatPrice(10)))
+	})
+
+	key := historicalCacheKey("X", "2020-01-01", "2020-01-31")
+	stale, _ := testSource{"X": weekdayRows("2020-01-01", "2020-01-15", flatPrice(10))}.HistoricalData("X", "2020-01-01", "2020-01-31")
+	stale.FetchedAt = time.Now().Add(-time.Hour)
+	err := (&FileCache{Dir: "."}).Set(key, stale)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Processes of their own sharing the directory, each with the stale
+	// file in memory already
+	const n = 8
+	caches := make([]*LRUCache, n)
+	for i := range caches {
+		caches[i] = NewLRUCache(8, &FileCache{Dir: "."})
+		caches[i].Get(key)
+	}
+
+	var wg sync.WaitGroup
+	rows := make([]int, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ndr := GetNASDAQHistoricialDataCached(caches[i], "X", "2020-01-01", "2020-01-31", true, false)
+			rows[i] = len(ndr.Data.TradesTable.Rows)
+		}(i)
+	}
+	wg.Wait()
+
+	if requests != 1 {
+		t.Errorf("%d requests revalidating a stale file from %d processes, want one", requests, n)
+	}
+	for i, r := range rows {
+		if r != 23 {
+			t.Errorf("process %d got %d rows, want the 23 refreshed", i, r)
+		}
+		if got, _, _ := caches[i].Get(key); len(got.Data.TradesTable.Rows) != 23 {
+			t.Errorf("process %d kept %d rows in memory, want the 23 refreshed", i, len(got.Data.TradesTable.Rows))
+		}
+	}
+}
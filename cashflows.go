@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// CashFlow is money going into the portfolio, negative, or its value coming
+// out at the end, positive, as spreadsheet XIRR functions expect.
+type CashFlow struct {
+	Date   time.Time
+	Amount float64
+}
+
+// CashFlows returns the contributions to all positions summed up by date, in
+// ascending order, followed by TotalReturn on the end date. Rebalancing
+// trades and reinvested dividends stay within the portfolio and aren't cash
+// flows.
+func (dp *DCAPortfolio) CashFlows() []CashFlow {
+	byDate := make(map[time.Time]float64)
+	for _, d := range dp.Positions {
+		for _, t := range d.Transactions {
+			if !t.Rebalance && !t.Dividend {
+				byDate[t.Date] -= t.Amount
+			}
+		}
+	}
+
+	var flows []CashFlow
+	for date, amount := range byDate {
+		flows = append(flows, CashFlow{Date: date, Amount: amount})
+	}
+	sort.Slice(flows, func(i, j int) bool { return flows[i].Date.Before(flows[j].Date) })
+
+	return append(flows, CashFlow{Date: dp.To, Amount: dp.TotalReturn})
+}
+
+// contributions returns the money put into the portfolio by date, see
+// CashFlows.
+func (dp *DCAPortfolio) contributions() []Contribution {
+	var cs []Contribution
+	for _, cf := range dp.CashFlows() {
+		if cf.Amount < 0 {
+			cs = append(cs, Contribution{Date: cf.Date, Amount: -cf.Amount})
+		}
+	}
+	return cs
+}
+
+// WriteCashFlowsCSV writes the portfolio's cash flows to a CSV file with
+// date and amount columns.
+func WriteCashFlowsCSV(file string, flows []CashFlow) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	cw := csv.NewWriter(f)
+	cw.Write([]string{"date", "amount"})
+	for _, cf := range flows {
+		cw.Write([]string{cf.Date.Format("2006-01-02"), strconv.FormatFloat(cf.Amount, 'f', 2, 64)})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFinalCashFlowIsTheTotalReturn(t *testing.T) {
+	source := testSource{
+		"X": weekdayRows("2020-01-01", "2020-06-30", func(t time.Time) float64 { return 10 + float64(t.YearDay())/10 }),
+		"Y": weekdayRows("2020-01-01", "2020-06-30", flatPrice(50)),
+	}
+	dp := NewDCAPortfolio([]SymbolSpec{{Symbol: "X"}, {Symbol: "Y"}}, "2020-01-01", "2020-06-30", Monthly, 300, Options{Source: source})
+
+	flows := dp.CashFlows()
+	last := flows[len(flows)-1]
+	if last.Amount != dp.TotalReturn || !last.Date.Equal(dp.To) {
+		t.Errorf("final cash flow %v on %s, want the total return %v on %s", last.Amount, last.Date.Format("2006-01-02"), dp.TotalReturn, dp.To.Format("2006-01-02"))
+	}
+
+	// Both positions' purchases summed up by date
+	var invested float64
+	for i, cf := range flows[:len(flows)-1] {
+		if cf.Amount >= 0 {
+			t.Errorf("contribution %v on %s isn't negative", cf.Amount, cf.Date.Format("2006-01-02"))
+		}
+		if i > 0 && !cf.Date.After(flows[i-1].Date) {
+			t.Errorf("cash flow on %s after %s", cf.Date.Format("2006-01-02"), flows[i-1].Date.Format("2006-01-02"))
+		}
+		invested -= cf.Amount
+	}
+	if len(flows) != 7 || math.Abs(invested-dp.TotalInvested) > 1e-6 {
+		t.Errorf("%d cash flows contributing %v, want 6 contributions of the %v invested and the total return", len(flows), invested, dp.TotalInvested)
+	}
+
+	file := filepath.Join(t.TempDir(), "cashflows.csv")
+	err := WriteCashFlowsCSV(file, flows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if lines[0] != "date,amount" || !strings.HasPrefix(lines[1], "2020-01-0") || !strings.HasPrefix(lines[len(lines)-1], "2020-06-30,") {
+		t.Errorf("cash flows CSV:\n%s", b)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+type SymbolComparison struct {
+	Symbol        string
+	From          time.Time
+	To            time.Time
+	TotalInvested float64
+	TotalReturn   float64
+	PNL           float64
+	CAGR          float64
+	Volatility    float64
+}
+
+// CompareSymbols DCAs the whole spend into each of the symbols on its own,
+// keeping their overrides, to rank them as candidates for a portfolio. The
+// results are sorted by PNL, best first.
+func CompareSymbols(specs []SymbolSpec, fromDate, toDate string, f Frequency, spend float64, opts Options) []SymbolComparison {
+	opts.Benchmark = ""
+	opts.Holdings = nil
+
+	var cs []SymbolComparison
+
+	for _, ss := range DedupeSymbolSpecs(specs) {
+		from, to, freq, amount, er := fromDate, toDate, f, spend, opts.ExpenseRatio
+		if ss.From != "" {
+			from = ss.From
+		}
+		if ss.To != "" {
+			to = ss.To
+		}
+		if ss.Frequency != 0 {
+			freq = ss.Frequency
+		}
+		if ss.Amount != 0 {
+			amount = ss.Amount
+		}
+		if ss.ExpenseRatio != 0 {
+			er = ss.ExpenseRatio
+		}
+
+		d := NewDCA(ss.Symbol, from, to, freq, amount, opts.withExpenseRatio(er))
+
+		cs = append(cs, SymbolComparison{
+			Symbol:        d.Symbol,
+			From:          d.From,
+			To:            d.To,
+			TotalInvested: d.TotalInvested,
+			TotalReturn:   d.TotalReturn,
+			PNL:           d.PNL,
+			CAGR:          d.CAGR,
+			Volatility:    d.Volatility,
+		})
+	}
+
+	sort.SliceStable(cs, func(i, j int) bool { return cs[i].PNL > cs[j].PNL })
+
+	return cs
+}
+
+func PrintSymbolComparison(cs []SymbolComparison) {
+	width := len("Symbol")
+	for _, c := range cs {
+		if len(c.Symbol) > width {
+			width = len(c.Symbol)
+		}
+	}
+
+	printer.Fprintf(output, "Rank  %-*s  %-10s   %-16s  %-16s  %10s   %10s   %10s\n", width, "Symbol", "Start", "Invested", "Return", "PNL", "CAGR", "Volatility")
+	for i, c := range cs {
+		printer.Fprintf(output, "%4d  %-*s  %s   %-16s  %-16s  %8.02f %%   %8.02f %%   %8.02f %%\n",
+			i+1, width, c.Symbol, c.From.Format("2006-01-02"), Money(c.TotalInvested), Money(c.TotalReturn), c.PNL, c.CAGR, c.Volatility)
+	}
+	printer.Fprintf(output, "\n")
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompareSymbols(t *testing.T) {
+	source := testSource{
+		"UP":   weekdayRows("2020-01-01", "2020-12-31", func(t time.Time) float64 { return 10 + float64(t.YearDay())/10 }),
+		"FLAT": weekdayRows("2020-01-01", "2020-12-31", flatPrice(10)),
+		"DOWN": weekdayRows("2020-01-01", "2020-12-31", func(t time.Time) float64 { return 50 - float64(t.YearDay())/10 }),
+	}
+	specs := []SymbolSpec{{Symbol: "DOWN"}, {Symbol: "FLAT"}, {Symbol: "UP"}, {Symbol: "FLAT"}}
+
+	cs := CompareSymbols(specs, "2020-01-01", "2020-12-31", Monthly, 100, Options{Source: source})
+
+	if len(cs) != 3 {
+		t.Fatalf("%d rows, want one for each symbol", len(cs))
+	}
+	// Best first
+	for i, symbol := range []string{"UP", "FLAT", "DOWN"} {
+		c := cs[i]
+		d := NewDCA(symbol, "2020-01-01", "2020-12-31", Monthly, 100, Options{Source: source})
+		if c.Symbol != symbol || c.TotalInvested != 1200 || c.TotalReturn != d.TotalReturn || c.PNL != d.PNL || c.CAGR != d.CAGR {
+			t.Errorf("row %d is %+v, want %s investing the whole 1200 on its own", i+1, c, symbol)
+		}
+	}
+
+	got := captureOutput(t, func() { PrintSymbolComparison(cs) })
+	if lines := strings.Split(strings.TrimSpace(got), "\n"); len(lines) != 4 || !strings.HasPrefix(lines[1], "   1  UP      2020-01-01") {
+		t.Errorf("printed:\n%s", got)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Contribution is an amount invested on Date.
+type Contribution struct {
+	Date   time.Time
+	Amount float64
+}
+
+// LoadContributions reads a CSV file with the columns date as YYYY-MM-DD and
+// amount invested on it, e.g. "2024-01-15,500". An optional header row is
+// skipped. The contributions are returned in date order.
+func LoadContributions(file string) ([]Contribution, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cr := csv.NewReader(f)
+	cr.FieldsPerRecord = 2
+	cr.TrimLeadingSpace = true
+	cr.Comment = '#'
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not read contributions file %s: %w", file, err)
+	}
+
+	var contributions []Contribution
+
+	for i, rec := range records {
+		t, err := time.Parse("2006-01-02", strings.TrimSpace(rec[0]))
+		if err != nil {
+			if i == 0 {
+				continue // Header
+			}
+			return nil, fmt.Errorf("contributions file %s: invalid date '%s'", file, rec[0])
+		}
+		amount, err := ParseUSD(rec[1])
+		if err != nil || amount <= 0 {
+			return nil, fmt.Errorf("contributions file %s: invalid amount '%s' on %s", file, rec[1], rec[0])
+		}
+
+		contributions = append(contributions, Contribution{Date: t, Amount: amount})
+	}
+	if len(contributions) == 0 {
+		return nil, fmt.Errorf("contributions file %s has no contributions", file)
+	}
+
+	sort.SliceStable(contributions, func(i, j int) bool { return contributions[i].Date.Before(contributions[j].Date) })
+
+	return contributions, nil
+}
+
+// nextContribution moves on to the first contribution on or after t and
+// returns its date, or the end date if there are none left. The ones moved
+// past are logged as skipped.
+func (r *dcaRun) nextContribution(t time.Time) time.Time {
+	cs := r.opts.Contributions
+	for r.contribution < len(cs) && cs[r.contribution].Date.Before(t) {
+		log.Printf("warning: skipping %s contribution of %s on %s, before its first trading day %s",
+			r.d.Symbol, Money(cs[r.contribution].Amount), cs[r.contribution].Date.Format("2006-01-02"), t.Format("2006-01-02"))
+		r.contribution++
+	}
+	if r.contribution == len(cs) {
+		return r.d.To
+	}
+	return cs[r.contribution].Date
+}
+
+// warnContributionsAfter logs the contributions dated after the end date,
+// which won't be invested.
+func (r *dcaRun) warnContributionsAfter(to time.Time) {
+	for _, c := range r.opts.Contributions {
+		if c.Date.After(to) {
+			log.Printf("warning: skipping %s contribution of %s on %s, after the end date %s",
+				r.d.Symbol, Money(c.Amount), c.Date.Format("2006-01-02"), to.Format("2006-01-02"))
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContributionsFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "contributions.csv")
+	err := os.WriteFile(file, []byte("date,amount\n2020-03-02,\"$1,000.00\"\n2020-01-06,100\n2020-02-03,200\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cs, err := LoadContributions(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cs) != 3 || !cs[0].Date.Equal(ISODateToTime("2020-01-06")) {
+		t.Fatalf("contributions %v, want three in date order", cs)
+	}
+
+	prices := map[string]float64{"2020-01-06": 10, "2020-02-03": 20, "2020-03-02": 50}
+	source := testSource{"X": weekdayRows("2020-01-01", "2020-03-31", func(t time.Time) float64 {
+		if p, ok := prices[t.Format("2006-01-02")]; ok {
+			return p
+		}
+		return 25
+	})}
+
+	d := NewDCA("X", "2020-01-01", "2020-03-31", Monthly, 500, Options{Source: source, Contributions: cs})
+
+	if d.TotalInvested != 1300 {
+		t.Errorf("invested %v, want 1300", d.TotalInvested)
+	}
+	if len(d.Transactions) != 3 {
+		t.Errorf("%d purchases, want 3", len(d.Transactions))
+	}
+	if want := 100.0/10 + 200.0/20 + 1000.0/50; !approx(d.Units, want) {
+		t.Errorf("%v units, want %v", d.Units, want)
+	}
+}
+
+func TestContributionsOnAndOutsideThePeriod(t *testing.T) {
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	source := testSource{"X": weekdayRows("2020-02-03", "2020-03-31", flatPrice(10))}
+	cs := []Contribution{
+		{Date: ISODateToTime("2020-01-15"), Amount: 100}, // Before inception
+		{Date: ISODateToTime("2020-02-14"), Amount: 200},
+		{Date: ISODateToTime("2020-03-31"), Amount: 300}, // On the end date
+		{Date: ISODateToTime("2020-04-15"), Amount: 400}, // After the end date
+	}
+
+	d := NewDCA("X", "2020-01-01", "2020-03-31", Monthly, 500, Options{Source: source, Contributions: cs})
+
+	if d.TotalInvested != 500 {
+		t.Errorf("invested %v, want the 500 contributed on and before the end date", d.TotalInvested)
+	}
+	for _, skipped := range []string{"$100.00 on 2020-01-15", "$400.00 on 2020-04-15"} {
+		if !strings.Contains(logged.String(), "contribution of "+skipped) {
+			t.Errorf("no warning about skipping the contribution of %s, logged:\n%s", skipped, logged.String())
+		}
+	}
+}
+
+func approx(a, b float64) bool {
+	return a-b < 1e-9 && b-a < 1e-9
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type CorrelationMatrix struct {
+	Symbols []string
+	// Matrix[i][j] is the correlation between the daily returns of
+	// Symbols[i] and Symbols[j].
+	Matrix [][]float64
+}
+
+// NewCorrelationMatrix correlates the daily returns of every pair of symbols
+// between the given dates. Each pair is aligned on the trading days both of
+// them have prices on, so symbols listed later are only compared from their
+// listing on.
+func NewCorrelationMatrix(symbols []string, fromDate, toDate string, opts Options) (*CorrelationMatrix, error) {
+	prices := make([]map[time.Time]float64, len(symbols))
+
+	for i, symbol := range symbols {
+		nd, err := opts.source().HistoricalData(symbol, fromDate, toDate)
+		if err != nil {
+			return nil, err
+		}
+
+		prices[i] = make(map[time.Time]float64)
+		for _, r := range nd.WithoutMissingPrices().Data.TradesTable.Rows {
+			if p := r.AvgPrice(); p > 0 {
+				prices[i][NASDAQDateToTime(r.Date)] = p
+			}
+		}
+	}
+
+	m := &CorrelationMatrix{Symbols: symbols, Matrix: make([][]float64, len(symbols))}
+	for i := range symbols {
+		m.Matrix[i] = make([]float64, len(symbols))
+		m.Matrix[i][i] = 1
+	}
+
+	for i := range symbols {
+		for j := i + 1; j < len(symbols); j++ {
+			c, err := correlateReturns(prices[i], prices[j])
+			if err != nil {
+				return nil, fmt.Errorf("could not correlate %s and %s: %w", symbols[i], symbols[j], err)
+			}
+			m.Matrix[i][j], m.Matrix[j][i] = c, c
+		}
+	}
+
+	return m, nil
+}
+
+// correlateReturns returns the Pearson correlation of the returns between the
+// dates both a and b have prices on.
+func correlateReturns(a, b map[time.Time]float64) (float64, error) {
+	var dates []time.Time
+	for t := range a {
+		if _, ok := b[t]; ok {
+			dates = append(dates, t)
+		}
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+
+	if len(dates) < 3 {
+		return 0, fmt.Errorf("only %d trading days in common", len(dates))
+	}
+
+	var ra, rb []float64
+	for i := 1; i < len(dates); i++ {
+		ra = append(ra, a[dates[i]]/a[dates[i-1]]-1)
+		rb = append(rb, b[dates[i]]/b[dates[i-1]]-1)
+	}
+
+	var meanA, meanB float64
+	for i := range ra {
+		meanA += ra[i]
+		meanB += rb[i]
+	}
+	meanA /= float64(len(ra))
+	meanB /= float64(len(rb))
+
+	var cov, varA, varB float64
+	for i := range ra {
+		cov += (ra[i] - meanA) * (rb[i] - meanB)
+		varA += (ra[i] - meanA) * (ra[i] - meanA)
+		varB += (rb[i] - meanB) * (rb[i] - meanB)
+	}
+	if varA == 0 || varB == 0 {
+		return 0, fmt.Errorf("prices don't change")
+	}
+
+	return cov / math.Sqrt(varA*varB), nil
+}
+
+// WriteCSV writes the matrix with a header row and column of the symbols.
+func (m *CorrelationMatrix) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	cw.Write(append([]string{""}, m.Symbols...))
+	for i, row := range m.Matrix {
+		rec := []string{m.Symbols[i]}
+		for _, c := range row {
+			rec = append(rec, strconv.FormatFloat(c, 'f', 4, 64))
+		}
+		cw.Write(rec)
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
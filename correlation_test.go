@@ -0,0 +1,50 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCorrelationOfPerfectlyCorrelatedSymbols(t *testing.T) {
+	price := func(t time.Time) float64 { return alternatingPrice(5)(t) + float64(t.YearDay())/10 }
+	source := testSource{
+		"A": weekdayRows("2020-01-01", "2020-06-30", price),
+		// Twice the price of A, listed a month later
+		"B": weekdayRows("2020-02-01", "2020-06-30", func(t time.Time) float64 { return 2 * price(t) }),
+		// Moving up when A moves down
+		"C": weekdayRows("2020-01-01", "2020-06-30", func(t time.Time) float64 { return 300 - price(t) }),
+	}
+
+	m, err := NewCorrelationMatrix([]string{"A", "B", "C"}, "2020-01-01", "2020-06-30", Options{Source: source})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c := m.Matrix[0][1]; math.Abs(c-1) > 1e-3 {
+		t.Errorf("A and B correlated %v, want about 1", c)
+	}
+	if c := m.Matrix[0][2]; c > -0.9 {
+		t.Errorf("A and C correlated %v, want close to -1", c)
+	}
+	for i := range m.Matrix {
+		if m.Matrix[i][i] != 1 {
+			t.Errorf("%s correlated %v with itself", m.Symbols[i], m.Matrix[i][i])
+		}
+		for j := range m.Matrix {
+			if m.Matrix[i][j] != m.Matrix[j][i] {
+				t.Errorf("matrix isn't symmetric: %v", m.Matrix)
+			}
+		}
+	}
+}
+
+func TestCorrelationOfFlatPrices(t *testing.T) {
+	source := testSource{
+		"A":    weekdayRows("2020-01-01", "2020-06-30", alternatingPrice(5)),
+		"FLAT": weekdayRows("2020-01-01", "2020-06-30", flatPrice(10)),
+	}
+	if _, err := NewCorrelationMatrix([]string{"A", "FLAT"}, "2020-01-01", "2020-06-30", Options{Source: source}); err == nil {
+		t.Error("no error correlating a flat price")
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CSVFileSource reads a single price series from a CSV file, see
+// ReadPriceCSV for the expected columns. The series is returned for any
+// symbol requested from it.
+type CSVFileSource struct {
+	File string
+}
+
+// Symbol returns the name of the series, which is the file name without its
+// extension.
+func (cs *CSVFileSource) Symbol() string {
+	base := filepath.Base(cs.File)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+func (cs *CSVFileSource) HistoricalData(symbol, fromDate, toDate string) (*NASDAQHistoricalAPIResponse, error) {
+	return readPriceCSVBetween(cs.File, symbol, fromDate, toDate)
+}
+
+// CSVDirSource reads the trading data for each symbol from a <SYMBOL>.csv
+// file in Dir, see ReadPriceCSV for the expected columns.
+type CSVDirSource struct {
+	Dir string
+}
+
+func (cs *CSVDirSource) HistoricalData(symbol, fromDate, toDate string) (*NASDAQHistoricalAPIResponse, error) {
+	file := filepath.Join(cs.Dir, strings.ToUpper(symbol)+".csv")
+	return readPriceCSVBetween(file, symbol, fromDate, toDate)
+}
+
+func readPriceCSVBetween(file, symbol, fromDate, toDate string) (*NASDAQHistoricalAPIResponse, error) {
+	ndr, err := ReadPriceCSV(file)
+	if err != nil {
+		return nil, err
+	}
+
+	ndr.Data.Symbol = symbol
+	ndr.Data.TradesTable.Rows = RowsBetween(ndr.Data.TradesTable.Rows, ISODateToTime(fromDate), ISODateToTime(toDate))
+	ndr.Data.TotalRecords = int64(len(ndr.Data.TradesTable.Rows))
+
+	return ndr, nil
+}
+
+// ReadPriceCSV reads a price series CSV file into a NASDAQ API response, with
+// the rows sorted newest first. The first row must be a header naming the
+// columns, in any order:
+//
+//   - date: the trading day as YYYY-MM-DD, required
+//   - close: the closing price, required unless a price column is given
+//   - open, high, low: optional, default to the closing price
+//   - volume: optional
+//   - price: a single price per day, used for all of the above
+//
+// Prices may be prefixed with "$".
+func ReadPriceCSV(file string) (*NASDAQHistoricalAPIResponse, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cr := csv.NewReader(f)
+	cr.TrimLeadingSpace = true
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not read CSV file %s: %w", file, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("CSV file %s is empty", file)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["date"]; !ok {
+		return nil, fmt.Errorf("CSV file %s is missing a 'date' column", file)
+	}
+	closeColumn := "close"
+	if _, ok := columns["price"]; ok {
+		closeColumn = "price"
+	} else if _, ok := columns["close"]; !ok {
+		return nil, fmt.Errorf("CSV file %s is missing a 'close' or 'price' column", file)
+	}
+
+	ndr := new(NASDAQHistoricalAPIResponse)
+
+	for line, rec := range records[1:] {
+		t, err := time.Parse("2006-01-02", rec[columns["date"]])
+		if err != nil {
+			return nil, fmt.Errorf("CSV file %s line %d: invalid date '%s'", file, line+2, rec[columns["date"]])
+		}
+
+		td := &TradingData{Date: t.Format(nasdaqDateLayout)}
+
+		for _, field := range []struct {
+			column string
+			value  *string
+		}{
+			{closeColumn, &td.Close},
+			{"open", &td.Open},
+			{"high", &td.High},
+			{"low", &td.Low},
+		} {
+			i, ok := columns[field.column]
+			if !ok || (closeColumn == "price" && field.column != "price") {
+				*field.value = td.Close
+				continue
+			}
+			_, err = ParseUSD(rec[i])
+			if err != nil && err != ErrMissingValue {
+				return nil, fmt.Errorf("CSV file %s line %d: %w", file, line+2, err)
+			}
+			*field.value = rec[i]
+		}
+
+		if i, ok := columns["volume"]; ok {
+			td.Volume = rec[i]
+		}
+
+		ndr.Data.TradesTable.Rows = append(ndr.Data.TradesTable.Rows, td)
+	}
+
+	rows := ndr.Data.TradesTable.Rows
+	sort.Slice(rows, func(i, j int) bool {
+		return NASDAQDateToTime(rows[i].Date).After(NASDAQDateToTime(rows[j].Date))
+	})
+	ndr.Data.TotalRecords = int64(len(rows))
+
+	return ndr, nil
+}
+
+// RowsAscending returns rows, which are newest first like lookups such as
+// PriceCloseToDate expect, oldest first as exports and charts want them. The
+// rows themselves aren't reordered.
+func RowsAscending(rows []*TradingData) []*TradingData {
+	ascending := make([]*TradingData, len(rows))
+	for i, r := range rows {
+		ascending[len(rows)-1-i] = r
+	}
+	return ascending
+}
+
+// RowsBetween returns the rows dated from from to to, inclusive.
+func RowsBetween(rows []*TradingData, from, to time.Time) []*TradingData {
+	var between []*TradingData
+
+	for _, r := range rows {
+		t := NASDAQDateToTime(r.Date)
+		if t.Before(from) || t.After(to) {
+			continue
+		}
+		between = append(between, r)
+	}
+
+	return between
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPortfolioFromCSVFiles(t *testing.T) {
+	dir := t.TempDir()
+	// Oldest first, in another column order and with a missing volume
+	writeFile(t, filepath.Join(dir, "AAA.csv"), "Date,Close,Open,High,Low,Volume\n"+
+		"2020-01-02,$10.00,$10.00,$10.00,$10.00,100\n"+
+		"2020-02-03,$20.00,$20.00,$20.00,$20.00,\n"+
+		"2020-03-02,$40.00,$40.00,$40.00,$40.00,100\n")
+	writeFile(t, filepath.Join(dir, "BBB.csv"), "date,price\n"+
+		"2020-03-02,25\n"+
+		"2020-02-03,50\n"+
+		"2020-01-02,100\n")
+
+	dp := NewDCAPortfolio([]SymbolSpec{{Symbol: "AAA"}, {Symbol: "BBB"}}, "2020-01-01", "2020-03-31", Monthly, 200, Options{Source: &CSVDirSource{Dir: dir}})
+
+	if len(dp.Positions) != 2 {
+		t.Fatalf("%d positions, want 2", len(dp.Positions))
+	}
+	aaa, bbb := dp.Positions[0], dp.Positions[1]
+	if want := 100.0/10 + 100.0/20 + 100.0/40; !approx(aaa.Units, want) || !approx(aaa.TotalReturn, want*40) {
+		t.Errorf("AAA %v units worth %v, want %v worth %v", aaa.Units, aaa.TotalReturn, want, want*40)
+	}
+	if want := 100.0/100 + 100.0/50 + 100.0/25; !approx(bbb.Units, want) || !approx(bbb.TotalReturn, want*25) {
+		t.Errorf("BBB %v units worth %v, want %v worth %v", bbb.Units, bbb.TotalReturn, want, want*25)
+	}
+	if dp.TotalInvested != 600 {
+		t.Errorf("invested %v, want 600", dp.TotalInvested)
+	}
+}
+
+func TestReadPriceCSVErrors(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"no date column": "day,close\n2020-01-02,10\n",
+		"no price":       "date,volume\n2020-01-02,100\n",
+		"bad date":       "date,close\n01/02/2020,10\n",
+		"bad price":      "date,close\n2020-01-02,ten\n",
+	} {
+		file := filepath.Join(dir, strings.ReplaceAll(name, " ", "-")+".csv")
+		writeFile(t, file, content)
+		if _, err := ReadPriceCSV(file); err == nil {
+			t.Errorf("no error for a CSV file with %s", name)
+		}
+	}
+}
+
+func TestRowsAscendingLeavesTheLookupOrder(t *testing.T) {
+	ndr, _ := testSource{"X": weekdayRows("2020-01-01", "2020-01-31", func(t time.Time) float64 { return float64(t.Day()) })}.HistoricalData("X", "2020-01-01", "2020-01-31")
+	rows := ndr.Data.TradesTable.Rows
+
+	ascending := RowsAscending(rows)
+	for i := 1; i < len(ascending); i++ {
+		if !NASDAQDateToTime(ascending[i].Date).After(NASDAQDateToTime(ascending[i-1].Date)) {
+			t.Fatalf("row %d on %s after %s", i, ascending[i].Date, ascending[i-1].Date)
+		}
+	}
+
+	if rows[0].Date != "01/31/2020" || rows[len(rows)-1].Date != "01/01/2020" {
+		t.Errorf("rows from %s to %s, want them left newest first", rows[0].Date, rows[len(rows)-1].Date)
+	}
+	// Saturday the 4th is priced at the Monday after
+	if p := ndr.PriceCloseToDate(ISODateToTime("2020-01-04")); p != 6 {
+		t.Errorf("price %v on 2020-01-04 after sorting a copy, want the 6 of 2020-01-06", p)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNoTradingData is returned for responses without any trading data rows.
+var ErrNoTradingData = errors.New("no trading data")
+
+// DataSource provides the historical trading data for a symbol between two
+// ISO dates (inclusive), with the rows ordered newest first like the NASDAQ
+// API returns them.
+type DataSource interface {
+	HistoricalData(symbol, fromDate, toDate string) (*NASDAQHistoricalAPIResponse, error)
+}
+
+// NASDAQSource fetches trading data from the NASDAQ API, caching responses
+// in Cache. Defaults to caching them as files in the working directory.
+// With Revalidate set cached responses are checked for changes using
+// conditional requests. With FailOnEmpty set a response without any rows is
+// an error, e.g. for an unknown ticker. With RefreshIncomplete set cached
+// responses that might be missing the last trading days are revalidated.
+// Concurrent requests for the same data share one fetch.
+type NASDAQSource struct {
+	Cache             Cache
+	Revalidate        bool
+	FailOnEmpty       bool
+	RefreshIncomplete bool
+}
+
+func (ns NASDAQSource) HistoricalData(symbol, fromDate, toDate string) (*NASDAQHistoricalAPIResponse, error) {
+	c := ns.Cache
+	if c == nil {
+		c = &FileCache{Dir: "."}
+	}
+	ndr, _ := nasdaqFetches.Do(historicalCacheKey(symbol, fromDate, toDate), func() (*NASDAQHistoricalAPIResponse, error) {
+		return GetNASDAQHistoricialDataCached(c, symbol, fromDate, toDate, ns.Revalidate, ns.RefreshIncomplete), nil
+	})
+	if ns.FailOnEmpty && len(ndr.Data.TradesTable.Rows) == 0 {
+		return nil, fmt.Errorf("%w returned for %s between %s and %s", ErrNoTradingData, symbol, fromDate, toDate)
+	}
+	return ndr, nil
+}
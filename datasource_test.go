@@ -0,0 +1,129 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// testSource serves in-memory trading data by symbol, with the rows of each
+// symbol newest first.
+type testSource map[string][]*TradingData
+
+func (ts testSource) HistoricalData(symbol, fromDate, toDate string) (*NASDAQHistoricalAPIResponse, error) {
+	rows, ok := ts[symbol]
+	if !ok {
+		return nil, fmt.Errorf("%w for %s", ErrNoTradingData, symbol)
+	}
+
+	ndr := &NASDAQHistoricalAPIResponse{}
+	ndr.Data.Symbol = symbol
+	ndr.Data.TradesTable.Rows = RowsBetween(rows, ISODateToTime(fromDate), ISODateToTime(toDate))
+	ndr.Data.TotalRecords = int64(len(ndr.Data.TradesTable.Rows))
+	return ndr, nil
+}
+
+// weekdayRows returns a row for every weekday between two ISO dates
+// (inclusive), newest first, with all of its prices set to price(t).
+func weekdayRows(fromDate, toDate string, price func(t time.Time) float64) []*TradingData {
+	var rows []*TradingData
+	for t := ISODateToTime(toDate); !t.Before(ISODateToTime(fromDate)); t = t.AddDate(0, 0, -1) {
+		if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+			continue
+		}
+		p := "$" + strconv.FormatFloat(price(t), 'f', 2, 64)
+		rows = append(rows, &TradingData{
+			Date:   t.Format(nasdaqDateLayout),
+			Close:  p,
+			Volume: "1,000",
+			Open:   p,
+			High:   p,
+			Low:    p,
+		})
+	}
+	return rows
+}
+
+// flatPrice returns a price function for weekdayRows that's always p.
+func flatPrice(p float64) func(time.Time) float64 {
+	return func(time.Time) float64 { return p }
+}
+
+// serveNASDAQ points the NASDAQ API at a test server running handler, from
+// a temporary working directory so that the file cache starts out empty.
+// Requests aren't retried.
+func serveNASDAQ(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chdir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	url, retries := nasdaqHistoricalURL, nasdaqRetries
+	nasdaqHistoricalURL = srv.URL + "/{ticker}?fromdate={fromDate}&todate={toDate}"
+	nasdaqRetries = 0
+
+	t.Cleanup(func() {
+		srv.Close()
+		os.Chdir(wd)
+		nasdaqHistoricalURL, nasdaqRetries = url, retries
+	})
+}
+
+// writeNASDAQResponse writes rows as a gzipped NASDAQ API response.
+func writeNASDAQResponse(w http.ResponseWriter, symbol string, rows []*TradingData) {
+	ndr := &NASDAQHistoricalAPIResponse{}
+	ndr.Data.Symbol = symbol
+	ndr.Data.TotalRecords = int64(len(rows))
+	ndr.Data.TradesTable.Rows = rows
+	if rows == nil {
+		ndr.Data.TradesTable.Rows = []*TradingData{}
+	}
+
+	w.Header().Set("content-encoding", "gzip")
+	gw := gzip.NewWriter(w)
+	json.NewEncoder(gw).Encode(ndr)
+	gw.Close()
+}
+
+// writeFile writes a test fixture to file.
+func writeFile(t *testing.T, file, content string) {
+	t.Helper()
+	err := os.WriteFile(file, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFailOnEmpty(t *testing.T) {
+	serveNASDAQ(t, func(w http.ResponseWriter, r *http.Request) {
+		writeNASDAQResponse(w, "NOPE", nil)
+	})
+
+	_, err := NASDAQSource{Cache: mapCache{}, FailOnEmpty: true}.HistoricalData("NOPE", "2020-01-01", "2020-01-31")
+	if !errors.Is(err, ErrNoTradingData) || err.Error() != "no trading data returned for NOPE between 2020-01-01 and 2020-01-31" {
+		t.Errorf("error %v for an empty response", err)
+	}
+
+	cache := mapCache{}
+	ndr, err := NASDAQSource{Cache: cache}.HistoricalData("NOPE", "2020-01-01", "2020-01-31")
+	if err != nil || len(ndr.Data.TradesTable.Rows) != 0 {
+		t.Errorf("%v, %v without FailOnEmpty, want the empty response", ndr, err)
+	}
+	if len(cache) != 0 {
+		t.Error("cached an empty response")
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dividend is a cash dividend per unit paid on Date.
+type Dividend struct {
+	Date   time.Time
+	Amount float64
+}
+
+// LoadDividends reads a CSV dividends file with the columns symbol, payment
+// date as YYYY-MM-DD and dividend per unit, e.g. "AAPL,2024-02-15,0.24". An
+// optional header row is skipped. The dividends of each symbol are returned
+// in date order.
+func LoadDividends(file string) (map[string][]Dividend, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cr := csv.NewReader(f)
+	cr.FieldsPerRecord = 3
+	cr.TrimLeadingSpace = true
+	cr.Comment = '#'
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not read dividends file %s: %w", file, err)
+	}
+
+	dividends := make(map[string][]Dividend)
+
+	for i, rec := range records {
+		t, err := time.Parse("2006-01-02", strings.TrimSpace(rec[1]))
+		if err != nil {
+			if i == 0 {
+				continue // Header
+			}
+			return nil, fmt.Errorf("dividends file %s: invalid date '%s' for %s", file, rec[1], rec[0])
+		}
+		amount, err := strconv.ParseFloat(strings.Replace(strings.TrimSpace(rec[2]), "$", "", 1), 64)
+		if err != nil || amount < 0 {
+			return nil, fmt.Errorf("dividends file %s: invalid dividend '%s' for %s", file, rec[2], rec[0])
+		}
+
+		symbol := strings.ToUpper(strings.TrimSpace(rec[0]))
+		dividends[symbol] = append(dividends[symbol], Dividend{Date: t, Amount: amount})
+	}
+
+	for _, divs := range dividends {
+		sort.Slice(divs, func(i, j int) bool { return divs[i].Date.Before(divs[j].Date) })
+	}
+
+	return dividends, nil
+}
+
+// reinvestDividends reinvests the dividends paid on the units held up until
+// t, less the DRIP fee, at the price on their payment date.
+func (r *dcaRun) reinvestDividends(t time.Time) {
+	d := r.d
+	divs := r.opts.Dividends[d.Symbol]
+
+	for ; r.dividends < len(divs) && !divs[r.dividends].Date.After(t); r.dividends++ {
+		div := divs[r.dividends]
+		if div.Date.Before(d.From) || d.Units == 0 {
+			continue
+		}
+
+		cash := d.Units * div.Amount
+		fee := cash * r.opts.DripFeePercent / 100
+		price := r.priceAt(div.Date)
+		if price <= 0 {
+			continue
+		}
+
+		d.Dividends += cash
+		d.DripFees += fee
+		d.Units += (cash - fee) / price
+		d.Transactions = append(d.Transactions, Transaction{
+			Date:     r.tradedOn(div.Date),
+			Price:    price,
+			Amount:   cash - fee,
+			Units:    (cash - fee) / price,
+			Dividend: true,
+			Fee:      fee,
+		})
+	}
+}
+
+// yieldOnCost returns the dividends paid in the last year up until to, before
+// DRIP fees, in percent of the total invested.
+func (d *DCA) yieldOnCost(to time.Time) float64 {
+	if d.TotalInvested <= 0 {
+		return 0
+	}
+
+	var income float64
+	yearAgo := to.AddDate(-1, 0, 0)
+	for _, t := range d.Transactions {
+		if t.Dividend && t.Date.After(yearAgo) && !t.Date.After(to) {
+			income += t.Amount + t.Fee
+		}
+	}
+
+	return income / d.TotalInvested * 100
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDripFeeReducesReinvestedUnits(t *testing.T) {
+	source := testSource{"X": weekdayRows("2020-01-01", "2020-12-31", flatPrice(10))}
+	dividends := map[string][]Dividend{"X": {{Date: ISODateToTime("2020-06-15"), Amount: 0.5}}}
+
+	// 100 units bought at the start, paid 50 in dividends
+	plain := NewDCA("X", "2020-01-01", "2020-12-31", Yearly, 1000, Options{Source: source, Dividends: dividends})
+	charged := NewDCA("X", "2020-01-01", "2020-12-31", Yearly, 1000, Options{Source: source, Dividends: dividends, DripFeePercent: 10})
+
+	if !approx(plain.Units, 105) {
+		t.Errorf("%v units without a DRIP fee, want 5 reinvested", plain.Units)
+	}
+	if !approx(charged.Units, 104.5) || !approx(charged.DripFees, 5) {
+		t.Errorf("%v units with %v in DRIP fees, want 4.5 reinvested after the 5 fee", charged.Units, charged.DripFees)
+	}
+	if charged.Dividends != plain.Dividends {
+		t.Errorf("dividends %v with a DRIP fee, want the %v paid before it", charged.Dividends, plain.Dividends)
+	}
+}
+
+func TestDividendIncomeAndYieldOnCost(t *testing.T) {
+	source := testSource{"X": weekdayRows("2020-01-01", "2021-12-31", flatPrice(10))}
+	dividends := map[string][]Dividend{"X": {
+		{Date: ISODateToTime("2020-06-15"), Amount: 0.5},
+		{Date: ISODateToTime("2021-06-15"), Amount: 0.4},
+	}}
+	d := NewDCA("X", "2020-01-01", "2021-12-31", Yearly, 1000, Options{Source: source, Dividends: dividends})
+
+	// 50 paid on the first 100 units, 82 on the 205 held a year later
+	if !approx(d.Dividends, 132) {
+		t.Errorf("dividends %v, want 132", d.Dividends)
+	}
+	// Only the last year's 82 on the 2,000 invested
+	if !approx(d.YieldOnCost, 4.1) {
+		t.Errorf("yield on cost %v %%, want 4.1 %%", d.YieldOnCost)
+	}
+
+	got := captureOutput(t, d.Print)
+	for _, want := range []string{"Dividends      : $132.00\n", "Yield on Cost  : 4.10 %\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("no %q in:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"runtime"
+	"runtime/debug"
+)
+
+// Exit codes, documented in the README.
+const (
+	ExitOK          = 0
+	ExitUsage       = 1 // Bad flags or input files, or any other error
+	ExitAPI         = 2 // The NASDAQ API couldn't be reached or responded with an error
+	ExitNoData      = 3 // No trading data for a symbol
+	ExitInterrupted = 130
+)
+
+// exit ends the program, replaceable to test the exit codes. Once
+// interrupted the interrupt handler exits instead, see handleInterrupts, so
+// that the exit doesn't depend on which of them gets there first.
+var exit = func(code int) {
+	if apiContext.Err() != nil {
+		select {}
+	}
+	os.Exit(code)
+}
+
+// APIError is a failed request to the NASDAQ API.
+type APIError struct {
+	URL string
+	Err error
+}
+
+func (e *APIError) Error() string {
+	err := e.Err
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		err = ue.Err // Without repeating the URL
+	}
+	return fmt.Sprintf("request to %s failed: %v", e.URL, err)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the exit code for an error the program ended with.
+func ExitCode(err error) int {
+	var apiErr *APIError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, context.Canceled):
+		return ExitInterrupted
+	case errors.Is(err, ErrNoTradingData):
+		return ExitNoData
+	case errors.As(err, &apiErr):
+		return ExitAPI
+	}
+	return ExitUsage
+}
+
+// exitOnPanic turns a panic ending the program into its exit code, see
+// ExitCode. Deferred by main.
+func exitOnPanic() {
+	r := recover()
+	if apiContext.Err() != nil {
+		// Interrupted, the requests in progress failed because of it if
+		// there were any
+		exit(ExitInterrupted)
+		return
+	}
+	if r == nil {
+		return
+	}
+
+	switch v := r.(type) {
+	case runtime.Error:
+		// A bug rather than an error to report
+		fmt.Fprintf(os.Stderr, "panic: %v\n\n%s", v, debug.Stack())
+		exit(ExitUsage)
+	case error:
+		log.Print(v)
+		exit(ExitCode(v))
+	default:
+		// Already logged by log.Panicf
+		exit(ExitUsage)
+	}
+}
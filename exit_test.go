@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// exited is panicked by exit while testing, to stop the code calling it.
+type exited struct{}
+
+// exitCodeOf runs f as main would and returns the code the program exits
+// with, ExitOK if f returns.
+func exitCodeOf(t *testing.T, f func()) (code int) {
+	t.Helper()
+
+	code = -1
+	saved := exit
+	exit = func(c int) {
+		if code < 0 {
+			code = c
+		}
+		panic(exited{})
+	}
+	defer func() {
+		exit = saved
+		if r := recover(); r != nil {
+			if _, ok := r.(exited); !ok {
+				panic(r)
+			}
+		}
+		if code < 0 {
+			code = ExitOK
+		}
+	}()
+
+	defer exitOnPanic()
+	f()
+	return code
+}
+
+func TestExitCodes(t *testing.T) {
+	serveNASDAQ(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/GOOD":
+			writeNASDAQResponse(w, "GOOD", weekdayRows("2020-01-01", "2020-01-31", flatPrice(10)))
+		case "/EMPTY":
+			writeNASDAQResponse(w, "EMPTY", nil)
+		default:
+			http.Error(w, "down", http.StatusInternalServerError)
+		}
+	})
+
+	for _, tc := range []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"success", []string{"-f", "2020-01-01", "-t", "2020-01-31", "-O", "good.csv", "GOOD"}, ExitOK},
+		{"usage", []string{"--no-such-flag", "GOOD"}, ExitUsage},
+		{"bad dates", []string{"-f", "2020-02-01", "-t", "2020-01-01", "GOOD"}, ExitUsage},
+		{"api", []string{"-f", "2020-01-01", "-t", "2020-01-31", "DOWN"}, ExitAPI},
+		{"no data", []string{"-f", "2020-01-01", "-t", "2020-01-31", "EMPTY"}, ExitNoData},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := exitCodeOf(t, func() { pricesMain(tc.args) })
+			if got != tc.want {
+				t.Errorf("exit code %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestExitCodeOfWrappedErrors(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want int
+	}{
+		{nil, ExitOK},
+		{errors.New("bad flag"), ExitUsage},
+		{fmt.Errorf("AAPL: %w", &APIError{URL: "https://api.nasdaq.com", Err: errors.New("timeout")}), ExitAPI},
+		{fmt.Errorf("%w available for AAPL", ErrNoTradingData), ExitNoData},
+	} {
+		if got := ExitCode(tc.err); got != tc.want {
+			t.Errorf("ExitCode(%v) = %d, want %d", tc.err, got, tc.want)
+		}
+	}
+}
+
+type panickingSource struct{ err error }
+
+func (ps panickingSource) HistoricalData(symbol, fromDate, toDate string) (*NASDAQHistoricalAPIResponse, error) {
+	panic(ps.err)
+}
+
+func TestSymbolChecksKeepTheErrorPanicked(t *testing.T) {
+	apiErr := &APIError{URL: "https://api.nasdaq.com", Err: errors.New("timeout")}
+
+	sc := checkSymbol("AAPL", "2020-01-01", "2020-12-31", panickingSource{apiErr})
+	if got := ExitCode(sc.Err); got != ExitAPI {
+		t.Errorf("exit code %d for %v, want %d", got, sc.Err, ExitAPI)
+	}
+}
+
+func TestInterruptedExitCode(t *testing.T) {
+	defer func(ctx context.Context) { apiContext = ctx }(apiContext)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	apiContext = ctx
+
+	// Whether the fetch in progress failed because of it, failed anyway or
+	// was done already
+	for name, f := range map[string]func(){
+		"cancelled fetch": func() { panic(&APIError{URL: "https://api.nasdaq.com", Err: context.Canceled}) },
+		"failed fetch":    func() { panic(&APIError{URL: "https://api.nasdaq.com", Err: errors.New("timeout")}) },
+		"done":            func() {},
+	} {
+		if got := exitCodeOf(t, f); got != ExitInterrupted {
+			t.Errorf("%s: exit code %d when interrupted, want %d", name, got, ExitInterrupted)
+		}
+	}
+
+	if got := ExitCode(fmt.Errorf("AAPL: %w", &APIError{URL: "https://api.nasdaq.com", Err: context.Canceled})); got != ExitInterrupted {
+		t.Errorf("ExitCode of a cancelled request = %d, want %d", got, ExitInterrupted)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+type FetchResult struct {
+	Symbol      string
+	TradingDays int
+	File        string
+}
+
+// FetchSymbols fetches the trading data of every symbol between fromDate and
+// toDate into the file cache fc without running a DCA, keeping data already
+// cached, so that the cache files can be shared to reproduce a backtest.
+func FetchSymbols(symbols []string, fromDate, toDate string, fc *FileCache) ([]FetchResult, error) {
+	err := ValidateDateRange(fromDate, toDate)
+	if err != nil {
+		return nil, err
+	}
+
+	source := NASDAQSource{Cache: fc, FailOnEmpty: true, RefreshIncomplete: true}
+	var results []FetchResult
+
+	for _, symbol := range symbols {
+		ndr, err := source.HistoricalData(symbol, fromDate, toDate)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, FetchResult{
+			Symbol:      symbol,
+			TradingDays: len(ndr.Data.TradesTable.Rows),
+			File:        fc.file(historicalCacheKey(symbol, fromDate, toDate)),
+		})
+	}
+
+	return results, nil
+}
+
+// fetchMain runs the fetch subcommand: nasdaq fetch [flags] <SYMBOL>...
+func fetchMain(args []string) {
+	fs := pflag.NewFlagSet("fetch", pflag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s fetch [flags] <SYMBOL>...\n\nFetches and caches the trading data of the symbols without running a DCA.\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fromDate := fs.StringP("from", "f", "2008-01-01", "Fetch trading data from this date")
+	toDate := fs.StringP("to", "t", Today(), "Fetch trading data up to this date")
+	cacheDir := fs.String("cache-dir", ".", "Directory to write the cache files to")
+
+	err := fs.Parse(args)
+	if err == pflag.ErrHelp {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		fs.Usage()
+		exit(ExitUsage)
+	}
+
+	handleInterrupts()
+	if fs.NArg() == 0 {
+		fs.Usage()
+		exit(ExitUsage)
+	}
+
+	err = os.MkdirAll(*cacheDir, 0755)
+	if err != nil {
+		panic(err)
+	}
+
+	var symbols []string
+	for _, s := range fs.Args() {
+		symbols = append(symbols, strings.ToUpper(strings.TrimSpace(s)))
+	}
+
+	results, err := FetchSymbols(symbols, *fromDate, *toDate, &FileCache{Dir: *cacheDir})
+	for _, fr := range results {
+		printer.Fprintf(output, "%-8s %6d trading days  %s\n", fr.Symbol, fr.TradingDays, fr.File)
+	}
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFetchCreatesACacheFilePerSymbol(t *testing.T) {
+	requests := serveCountedNASDAQ(t)
+	fc := &FileCache{Dir: filepath.Join(t.TempDir(), "fixtures")}
+	err := os.MkdirAll(fc.Dir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := FetchSymbols([]string{"X", "Y"}, "2020-01-01", "2020-01-31", fc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("results %v, want X and Y", results)
+	}
+	for i, symbol := range []string{"X", "Y"} {
+		fr := results[i]
+		if fr.Symbol != symbol || fr.TradingDays != 23 || fr.File != fc.file(historicalCacheKey(symbol, "2020-01-01", "2020-01-31")) {
+			t.Errorf("result %+v for %s", fr, symbol)
+		}
+		if _, err := os.Stat(fr.File); err != nil {
+			t.Errorf("no cache file for %s: %v", symbol, err)
+		}
+	}
+
+	// Fetched already, the cache files are kept as they are
+	_, err = FetchSymbols([]string{"X", "Y"}, "2020-01-01", "2020-01-31", fc)
+	if err != nil || requests() != 2 {
+		t.Errorf("%d requests fetching again, want the 2 first ones (%v)", requests(), err)
+	}
+}
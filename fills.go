@@ -0,0 +1,98 @@
+package main
+
+import (
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// RandomFill returns a price picked uniformly at random between the day's low
+// and high, modeling not knowing when during the day a purchase executes.
+// Days without a low or high fall back to AvgPrice.
+func (t *TradingData) RandomFill(rng *rand.Rand) float64 {
+	low := t.WeightedPrice(PriceWeights{Low: 1})
+	high := t.WeightedPrice(PriceWeights{High: 1})
+	if low <= 0 || high < low {
+		return t.AvgPrice()
+	}
+	return low + rng.Float64()*(high-low)
+}
+
+// fillRand returns the random number generator for the fills of symbol with
+// seed, so that a symbol gets the same fills whatever it's run together with.
+func fillRand(seed int64, symbol string) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(symbol))
+	return rand.New(rand.NewSource(seed ^ int64(h.Sum64())))
+}
+
+type FillSimulationRun struct {
+	Seed int64
+	PNL  float64
+	CAGR float64
+}
+
+type FillSimulation struct {
+	Runs    []FillSimulationRun
+	MeanPNL float64
+	StdDev  float64
+	// 90% of the runs had a PNL between P5PNL and P95PNL.
+	P5PNL  float64
+	P95PNL float64
+}
+
+// NewFillSimulation runs the same portfolio with random fills runs times,
+// seeded with seed, seed+1 and so on, to show how much the outcome depends on
+// the prices purchases happened to execute at.
+func NewFillSimulation(specs []SymbolSpec, fromDate, toDate string, f Frequency, spend float64, opts Options, seed int64, runs int) *FillSimulation {
+	opts.Source = &WindowSource{Source: opts.source(), From: fromDate, To: toDate}
+	opts.Benchmark = ""
+	opts.RandomFills = true
+
+	fs := new(FillSimulation)
+	var pnls []float64
+
+	for i := 0; i < runs; i++ {
+		opts.FillSeed = seed + int64(i)
+		dp := NewDCAPortfolio(specs, fromDate, toDate, f, spend, opts)
+
+		fs.Runs = append(fs.Runs, FillSimulationRun{Seed: opts.FillSeed, PNL: dp.PNL, CAGR: dp.CAGR})
+		fs.MeanPNL += dp.PNL
+		pnls = append(pnls, dp.PNL)
+	}
+
+	fs.MeanPNL /= float64(len(pnls))
+	if len(pnls) > 1 {
+		fs.StdDev = StdDev(pnls)
+	}
+
+	sort.Float64s(pnls)
+	fs.P5PNL = percentile(pnls, 5)
+	fs.P95PNL = percentile(pnls, 95)
+
+	return fs
+}
+
+// percentile returns the p-th percentile of sorted values, interpolating
+// between the closest ones.
+func percentile(sorted []float64, p float64) float64 {
+	pos := p / 100 * float64(len(sorted)-1)
+	i := int(math.Floor(pos))
+	if i+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[i] + (pos-float64(i))*(sorted[i+1]-sorted[i])
+}
+
+func (fs *FillSimulation) Print() {
+	printer.Fprintf(output, "Seed         PNL         CAGR\n")
+	for _, r := range fs.Runs {
+		printer.Fprintf(output, "%-8d   %8.02f %%   %6.02f %%\n", r.Seed, r.PNL, r.CAGR)
+	}
+	printer.Fprintf(output, "\n")
+	printer.Fprintf(output, "Runs           : %d\n", len(fs.Runs))
+	printer.Fprintf(output, "Mean PNL       : %.02f %%\n", fs.MeanPNL)
+	printer.Fprintf(output, "PNL Std Dev    : %.02f %%\n", fs.StdDev)
+	printer.Fprintf(output, "90%% Band       : %.02f %% - %.02f %%\n\n", fs.P5PNL, fs.P95PNL)
+}
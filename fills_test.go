@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestSeededRandomFills(t *testing.T) {
+	rows := weekdayRows("2020-01-01", "2020-12-31", flatPrice(100))
+	for _, r := range rows {
+		r.Low, r.High = "$90.00", "$110.00"
+	}
+	source := testSource{"X": rows}
+	fills := func(seed int64) []float64 {
+		d := NewDCA("X", "2020-01-01", "2020-12-31", Weekly, 100, Options{Source: source, RandomFills: true, FillSeed: seed})
+		var prices []float64
+		for _, tx := range d.Transactions {
+			prices = append(prices, tx.Price)
+		}
+		return prices
+	}
+
+	first, again, other := fills(42), fills(42), fills(43)
+
+	same := true
+	for i, p := range first {
+		if p < 90 || p > 110 {
+			t.Errorf("filled at %v, outside the day's range 90 - 110", p)
+		}
+		if p != again[i] {
+			t.Fatalf("fill %d at %v and %v with the same seed", i+1, p, again[i])
+		}
+		same = same && p == other[i]
+	}
+	if same {
+		t.Error("the same fills with another seed")
+	}
+
+	fs := NewFillSimulation([]SymbolSpec{{Symbol: "X"}}, "2020-01-01", "2020-12-31", Weekly, 100, Options{Source: source}, 42, 20)
+	if len(fs.Runs) != 20 || fs.Runs[0].Seed != 42 || fs.Runs[19].Seed != 61 {
+		t.Errorf("%d runs, want 20 seeded 42 to 61", len(fs.Runs))
+	}
+	if fs.StdDev <= 0 || fs.P5PNL > fs.MeanPNL || fs.MeanPNL > fs.P95PNL {
+		t.Errorf("mean PNL %v with std dev %v between %v and %v", fs.MeanPNL, fs.StdDev, fs.P5PNL, fs.P95PNL)
+	}
+}
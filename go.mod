@@ -4,5 +4,6 @@ go 1.21.0
 
 require (
 	github.com/spf13/pflag v1.0.5
+	golang.org/x/sync v0.10.0
 	golang.org/x/text v0.16.0
 )
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Tickers requested by CheckNASDAQAPI for each market, listed for as long as
+// the API existed.
+var healthCheckTickers = map[string]string{
+	"us":     "AAPL",
+	"nordic": "SSE3966",
+}
+
+type HealthCheck struct {
+	URL     string
+	Status  int
+	Latency time.Duration
+	Err     error
+}
+
+func (hc *HealthCheck) OK() bool {
+	return hc.Err == nil
+}
+
+// CheckNASDAQAPI makes a single request for the last week of trading data of
+// a well known ticker and reports whether a valid response came back, with
+// its HTTP status and how long it took.
+func CheckNASDAQAPI() *HealthCheck {
+	to := time.Now()
+	from := to.AddDate(0, 0, -7)
+
+	r := newNASDAQHistoricalRequest(healthCheckTickers[market.Name()], from.Format("2006-01-02"), to.Format("2006-01-02"))
+	hc := &HealthCheck{URL: r.URL.String()}
+
+	start := time.Now()
+	res, err := http.DefaultClient.Do(r)
+	if err != nil {
+		hc.Latency = time.Since(start)
+		hc.Err = err
+		return hc
+	}
+	defer res.Body.Close()
+
+	hc.Status = res.StatusCode
+
+	var body io.Reader = res.Body
+	if res.Header.Get("content-encoding") == "gzip" {
+		gr, err := gzip.NewReader(res.Body)
+		if err != nil {
+			hc.Err = err
+			return hc
+		}
+		body = gr
+	}
+
+	data, err := io.ReadAll(body)
+	hc.Latency = time.Since(start)
+	if err != nil {
+		hc.Err = err
+		return hc
+	}
+
+	if res.StatusCode != http.StatusOK {
+		hc.Err = fmt.Errorf("unexpected HTTP status %s", res.Status)
+		return hc
+	}
+
+	_, err = market.ParseHistorical(data)
+	if err != nil {
+		hc.Err = fmt.Errorf("could not parse response: %w", err)
+		return hc
+	}
+
+	return hc
+}
+
+func (hc *HealthCheck) Print() {
+	status := "OK"
+	if !hc.OK() {
+		status = "FAILED: " + hc.Err.Error()
+	}
+
+	printer.Fprintf(output, "URL            : %s\n", hc.URL)
+	printer.Fprintf(output, "HTTP Status    : %d\n", hc.Status)
+	printer.Fprintf(output, "Latency        : %s\n", hc.Latency.Round(time.Millisecond))
+	printer.Fprintf(output, "Result         : %s\n", status)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCheckNASDAQAPI(t *testing.T) {
+	serveNASDAQ(t, func(w http.ResponseWriter, r *http.Request) {
+		writeNASDAQResponse(w, "AAPL", weekdayRows("2020-01-01", "2020-01-07", flatPrice(10)))
+	})
+
+	hc := CheckNASDAQAPI()
+	if !hc.OK() || hc.Status != http.StatusOK {
+		t.Errorf("status %d with error %v, want 200 OK", hc.Status, hc.Err)
+	}
+	if hc.Latency <= 0 {
+		t.Errorf("latency %v, want it measured", hc.Latency)
+	}
+}
+
+func TestCheckNASDAQAPIForbidden(t *testing.T) {
+	serveNASDAQ(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Access Denied", http.StatusForbidden)
+	})
+
+	hc := CheckNASDAQAPI()
+	if hc.OK() || hc.Status != http.StatusForbidden {
+		t.Errorf("status %d with error %v, want 403 failing", hc.Status, hc.Err)
+	}
+	if got := captureOutput(t, hc.Print); !strings.Contains(got, "Result         : FAILED: unexpected HTTP status 403 Forbidden\n") {
+		t.Errorf("printed:\n%s", got)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Account is an existing holding of a symbol in an account, the units held
+// and what they cost in total. Name is empty for holdings not in a named
+// account.
+type Account struct {
+	Name      string
+	Symbol    string
+	Units     float64
+	CostBasis float64
+}
+
+// LoadHoldings reads a CSV holdings file. The first row may be a header
+// naming the columns, in any order:
+//
+//   - symbol: required
+//   - units: required
+//   - cost_basis: what the units cost in total, required
+//   - account: optional, the account label the units are held in
+//
+// Without a header the columns are symbol, units, cost basis and optionally
+// account, e.g. "AAPL,10,1500.00,ira".
+func LoadHoldings(file string) ([]Account, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cr := csv.NewReader(f)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	cr.Comment = '#'
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not read holdings file %s: %w", file, err)
+	}
+
+	columns := map[string]int{"symbol": 0, "units": 1, "cost_basis": 2, "account": 3}
+	header := make(map[string]int)
+	if len(records) > 0 {
+		for i, name := range records[0] {
+			name = strings.ToLower(strings.TrimSpace(name))
+			header[strings.NewReplacer(" ", "_", "-", "_").Replace(name)] = i
+		}
+	}
+	if _, ok := header["symbol"]; ok {
+		columns = header
+		for _, required := range []string{"symbol", "units", "cost_basis"} {
+			if _, ok := columns[required]; !ok {
+				return nil, fmt.Errorf("holdings file %s is missing a '%s' column", file, required)
+			}
+		}
+		records = records[1:]
+	}
+
+	var holdings []Account
+	seen := make(map[string]bool)
+
+	for line, rec := range records {
+		value := func(column string) string {
+			i, ok := columns[column]
+			if !ok || i >= len(rec) {
+				return ""
+			}
+			return strings.TrimSpace(rec[i])
+		}
+
+		a := Account{Name: value("account"), Symbol: strings.ToUpper(value("symbol"))}
+		if a.Symbol == "" {
+			return nil, fmt.Errorf("holdings file %s line %d: no symbol", file, line+1)
+		}
+
+		a.Units, err = strconv.ParseFloat(value("units"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("holdings file %s: invalid units '%s' for %s", file, value("units"), a.Symbol)
+		}
+		a.CostBasis, err = strconv.ParseFloat(strings.NewReplacer("$", "", ",", "").Replace(value("cost_basis")), 64)
+		if err != nil {
+			return nil, fmt.Errorf("holdings file %s: invalid cost basis '%s' for %s", file, value("cost_basis"), a.Symbol)
+		}
+		if a.Units < 0 || a.CostBasis < 0 {
+			return nil, fmt.Errorf("holdings file %s: units and cost basis for %s can't be negative", file, a.Symbol)
+		}
+
+		key := a.Symbol + ":" + a.Name
+		if seen[key] {
+			return nil, fmt.Errorf("holdings file %s: %s is listed more than once for the same account", file, a.Symbol)
+		}
+		seen[key] = true
+
+		holdings = append(holdings, a)
+	}
+
+	return holdings, nil
+}
+
+// holding returns the existing holding of symbol in account, if any.
+func (opts Options) holding(symbol, account string) (Account, bool) {
+	for _, h := range opts.Holdings {
+		if h.Symbol == symbol && h.Name == account {
+			return h, true
+		}
+	}
+	return Account{}, false
+}
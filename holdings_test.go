@@ -0,0 +1,82 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadHoldingsSeedsStartingUnits(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "holdings.csv")
+	writeFile(t, file, "account,symbol,units,cost basis\nira,x,10,\"$1,000.00\"\n,Y,4,50\n")
+
+	holdings, err := LoadHoldings(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Account{{Name: "ira", Symbol: "X", Units: 10, CostBasis: 1000}, {Symbol: "Y", Units: 4, CostBasis: 50}}
+	if len(holdings) != len(want) || holdings[0] != want[0] || holdings[1] != want[1] {
+		t.Fatalf("holdings %v, want %v", holdings, want)
+	}
+
+	source := testSource{
+		"X": weekdayRows("2020-01-01", "2020-03-31", flatPrice(100)),
+		"Y": weekdayRows("2020-01-01", "2020-03-31", flatPrice(20)),
+	}
+	// Only DCA:ing into Y, X is held on to
+	dp := NewDCAPortfolio([]SymbolSpec{{Symbol: "Y"}}, "2020-01-01", "2020-03-31", Monthly, 100, Options{Source: source, Holdings: holdings})
+
+	units := make(map[string]float64)
+	for _, d := range dp.Positions {
+		units[d.Symbol+":"+d.Account] = d.Units
+	}
+	if units["X:ira"] != 10 {
+		t.Errorf("%v units of X held in the ira, want the 10 imported", units["X:ira"])
+	}
+	if units["Y:"] < 4 {
+		t.Errorf("%v units of Y, want at least the 4 imported", units["Y:"])
+	}
+}
+
+func TestLoadHoldingsWithoutHeader(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "holdings.csv")
+	writeFile(t, file, "# symbol, units, cost basis\nAAPL, 10, 1500.00, ira\n")
+
+	holdings, err := LoadHoldings(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Account{Name: "ira", Symbol: "AAPL", Units: 10, CostBasis: 1500}); len(holdings) != 1 || holdings[0] != want {
+		t.Errorf("holdings %v, want %v", holdings, want)
+	}
+
+	for _, content := range []string{"AAPL,ten,1500\n", "AAPL,10,1500\naapl,1,100\n", "symbol,units\nAAPL,10\n", "AAPL,-1,100\n"} {
+		writeFile(t, file, content)
+		if _, err := LoadHoldings(file); err == nil {
+			t.Errorf("no error for holdings %q", content)
+		}
+	}
+}
+
+func TestImportedUnitsPlusDCAUnits(t *testing.T) {
+	source := testSource{"X": weekdayRows("2020-01-01", "2020-06-30", func(t time.Time) float64 { return 50 + float64(t.Month())*10 })}
+	holdings := []Account{{Symbol: "X", Units: 10, CostBasis: 400}}
+
+	dp := NewDCAPortfolio([]SymbolSpec{{Symbol: "X"}}, "2020-01-01", "2020-06-30", Monthly, 120, Options{Source: source, Holdings: holdings})
+
+	d := dp.Positions[0]
+	// 120 a month at 60, 70, ... 110
+	var bought float64
+	for m := 1; m <= 6; m++ {
+		bought += 120 / (50 + float64(m)*10)
+	}
+	if !approx(d.Units, 10+bought) {
+		t.Errorf("%v units, want the 10 imported and %v bought", d.Units, bought)
+	}
+	if d.TotalInvested != 400+6*120 {
+		t.Errorf("invested %v, want the 400 cost basis and 720 DCA:ed", d.TotalInvested)
+	}
+	if !approx(dp.TotalReturn, d.Units*110) {
+		t.Errorf("return %v, want all %v units at 110", dp.TotalReturn, d.Units)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// IncrementalSource keeps all trading data fetched for a symbol since a start
+// date in Cache, so that later runs only fetch the trading days after the
+// latest one cached from Source and add them to it. The latest cached day is
+// fetched again too, in case it was cached before trading closed.
+type IncrementalSource struct {
+	Source DataSource
+	Cache  Cache
+}
+
+func (is *IncrementalSource) HistoricalData(symbol, fromDate, toDate string) (*NASDAQHistoricalAPIResponse, error) {
+	key := historicalCacheKey(symbol, fromDate, "latest")
+
+	cached, ok, err := is.Cache.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	ndr := cached
+	if !ok || len(cached.Data.TradesTable.Rows) == 0 {
+		ndr, err = is.Source.HistoricalData(symbol, fromDate, toDate)
+		if err != nil {
+			return nil, err
+		}
+	} else if latest := cached.Data.TradesTable.Rows[0].Date; NASDAQDateToTime(latest).Format("2006-01-02") < toDate {
+		newer, err := is.Source.HistoricalData(symbol, NASDAQDateToTime(latest).Format("2006-01-02"), toDate)
+		if err != nil {
+			return nil, err
+		}
+		ndr = MergeHistoricalData(cached, newer)
+	}
+
+	if ndr != cached && len(ndr.Data.TradesTable.Rows) > 0 {
+		err = is.Cache.Set(key, ndr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	between := *ndr
+	between.Data.TradesTable.Rows = RowsBetween(ndr.Data.TradesTable.Rows, ISODateToTime(fromDate), ISODateToTime(toDate))
+	between.Data.TotalRecords = int64(len(between.Data.TradesTable.Rows))
+
+	return &between, nil
+}
+
+// MergeHistoricalData returns the rows of older and newer combined newest
+// first, with the rows of newer replacing those of older on the same dates.
+// Neither response is modified.
+func MergeHistoricalData(older, newer *NASDAQHistoricalAPIResponse) *NASDAQHistoricalAPIResponse {
+	merged := *newer
+	merged.Data.TradesTable.Rows = nil
+
+	seen := make(map[time.Time]bool)
+	for _, r := range newer.Data.TradesTable.Rows {
+		seen[NASDAQDateToTime(r.Date)] = true
+		merged.Data.TradesTable.Rows = append(merged.Data.TradesTable.Rows, r)
+	}
+	for _, r := range older.Data.TradesTable.Rows {
+		if !seen[NASDAQDateToTime(r.Date)] {
+			merged.Data.TradesTable.Rows = append(merged.Data.TradesTable.Rows, r)
+		}
+	}
+
+	rows := merged.Data.TradesTable.Rows
+	sort.SliceStable(rows, func(i, j int) bool {
+		return NASDAQDateToTime(rows[i].Date).After(NASDAQDateToTime(rows[j].Date))
+	})
+	merged.Data.TotalRecords = int64(len(rows))
+	if merged.Data.Symbol == "" {
+		merged.Data.Symbol = older.Data.Symbol
+	}
+
+	return &merged
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// mapCache is a Cache kept in memory.
+type mapCache map[string]*NASDAQHistoricalAPIResponse
+
+func (mc mapCache) Get(key string) (*NASDAQHistoricalAPIResponse, bool, error) {
+	ndr, ok := mc[key]
+	return ndr, ok, nil
+}
+
+func (mc mapCache) Set(key string, ndr *NASDAQHistoricalAPIResponse) error {
+	mc[key] = ndr
+	return nil
+}
+
+// recordingSource records the date ranges requested from Source.
+type recordingSource struct {
+	Source   DataSource
+	requests [][2]string
+}
+
+func (rs *recordingSource) HistoricalData(symbol, fromDate, toDate string) (*NASDAQHistoricalAPIResponse, error) {
+	rs.requests = append(rs.requests, [2]string{fromDate, toDate})
+	return rs.Source.HistoricalData(symbol, fromDate, toDate)
+}
+
+func TestIncrementalSourceMergesNewerRows(t *testing.T) {
+	cached, _ := testSource{"X": weekdayRows("2020-01-01", "2020-01-15", flatPrice(10))}.HistoricalData("X", "2020-01-01", "2020-01-15")
+	cache := mapCache{historicalCacheKey("X", "2020-01-01", "latest"): cached}
+
+	// The latest cached day was cached before trading closed
+	source := &recordingSource{Source: testSource{"X": weekdayRows("2020-01-15", "2020-01-31", flatPrice(20))}}
+	is := &IncrementalSource{Source: source, Cache: cache}
+
+	ndr, err := is.HistoricalData("X", "2020-01-01", "2020-01-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(source.requests) != 1 || source.requests[0] != [2]string{"2020-01-15", "2020-01-31"} {
+		t.Errorf("requested %v, want only 2020-01-15 - 2020-01-31", source.requests)
+	}
+	rows := ndr.Data.TradesTable.Rows
+	if len(rows) != 23 {
+		t.Fatalf("%d trading days, want the 23 weekdays of January", len(rows))
+	}
+	if rows[0].Date != "01/31/2020" || rows[len(rows)-1].Date != "01/01/2020" {
+		t.Errorf("rows from %s to %s, want newest first from 01/31/2020 to 01/01/2020", rows[len(rows)-1].Date, rows[0].Date)
+	}
+	for _, r := range rows {
+		want := "$10.00"
+		if !NASDAQDateToTime(r.Date).Before(time.Date(2020, 1, 15, 0, 0, 0, 0, time.UTC)) {
+			want = "$20.00"
+		}
+		if r.Close != want {
+			t.Errorf("close %s on %s, want %s", r.Close, r.Date, want)
+		}
+	}
+	if got := cache[historicalCacheKey("X", "2020-01-01", "latest")]; len(got.Data.TradesTable.Rows) != 23 {
+		t.Errorf("cached %d trading days, want 23", len(got.Data.TradesTable.Rows))
+	}
+}
+
+func TestIncrementalSourceKeysByMarket(t *testing.T) {
+	defer func(m Market) { market = m }(market)
+	market = NordicMarket{}
+
+	cache := mapCache{}
+	is := &IncrementalSource{Source: testSource{"X": weekdayRows("2020-01-01", "2020-01-31", flatPrice(10))}, Cache: cache}
+
+	_, err := is.HistoricalData("X", "2020-01-01", "2020-01-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache["nordic-"+CacheKey("X", "2020-01-01", "latest")]; !ok {
+		t.Error("not cached under the nordic market's key")
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Constituent lists are static snapshots, one ticker per line, and need to be
+// updated by hand as the indexes change.
+//
+//go:embed indexes/*.txt
+var indexFiles embed.FS
+
+// IndexNames returns the names of the indexes with known constituents.
+func IndexNames() []string {
+	entries, _ := indexFiles.ReadDir("indexes")
+
+	var names []string
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".txt"))
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// IndexSymbols returns the tickers of the constituents of an index, e.g.
+// "nasdaq100" or "dow30".
+func IndexSymbols(index string) ([]string, error) {
+	name := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(index))
+
+	data, err := indexFiles.ReadFile("indexes/" + name + ".txt")
+	if err != nil {
+		return nil, fmt.Errorf("unknown index '%s', expected one of: %s", index, strings.Join(IndexNames(), ", "))
+	}
+
+	var symbols []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		symbols = append(symbols, line)
+	}
+
+	return symbols, nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestIndexSymbols(t *testing.T) {
+	for index, want := range map[string]int{
+		"dow30":      30,
+		"Dow-30":     30,
+		"nasdaq100":  101, // Alphabet has two share classes
+		"NASDAQ 100": 101,
+	} {
+		symbols, err := IndexSymbols(index)
+		if err != nil {
+			t.Errorf("%s: %v", index, err)
+			continue
+		}
+		if len(symbols) != want {
+			t.Errorf("%s has %d symbols, want %d", index, len(symbols), want)
+		}
+		seen := make(map[string]bool)
+		for _, s := range symbols {
+			if seen[s] {
+				t.Errorf("%s lists %s more than once", index, s)
+			}
+			seen[s] = true
+		}
+	}
+
+	if _, err := IndexSymbols("sp500"); err == nil {
+		t.Error("no error for an unknown index")
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strconv"
+)
+
+// Trading days per year, used to spread annual rates over daily returns.
+const tradingDaysPerYear = 252
+
+// LeveragedSource builds a synthetic daily leveraged version of the series
+// from Source, like a 2x or 3x leveraged ETF. Each day's return is scaled by
+// Leverage and reduced by AnnualDecay (in percent, e.g. 1 for 1%) spread over
+// the trading days, modeling the costs of such funds. The synthetic series
+// starts at the same price as the original one.
+type LeveragedSource struct {
+	Source      DataSource
+	Leverage    float64
+	AnnualDecay float64
+}
+
+func (ls *LeveragedSource) HistoricalData(symbol, fromDate, toDate string) (*NASDAQHistoricalAPIResponse, error) {
+	nd, err := ls.Source.HistoricalData(symbol, fromDate, toDate)
+	if err != nil {
+		return nil, err
+	}
+	nd = nd.WithoutMissingPrices()
+
+	synthetic := *nd
+	rows := nd.Data.TradesTable.Rows
+	synthetic.Data.TradesTable.Rows = make([]*TradingData, len(rows))
+
+	dailyDecay := ls.AnnualDecay / 100 / tradingDaysPerYear
+	var price, prevPrice float64
+
+	// Rows are newest first
+	for i := len(rows) - 1; i >= 0; i-- {
+		p := rows[i].AvgPrice()
+		if i == len(rows)-1 {
+			price = p
+		} else if prevPrice > 0 {
+			price *= 1 + ls.Leverage*(p/prevPrice-1) - dailyDecay
+			if price < 0 {
+				price = 0
+			}
+		}
+		prevPrice = p
+
+		v := strconv.FormatFloat(price, 'f', -1, 64)
+		synthetic.Data.TradesTable.Rows[i] = &TradingData{
+			Date:   rows[i].Date,
+			Close:  v,
+			Volume: rows[i].Volume,
+			Open:   v,
+			High:   v,
+			Low:    v,
+		}
+	}
+
+	return &synthetic, nil
+}
+
+// ExpenseRatioSource reduces the prices of Source by an annual expense ratio
+// of Percent spread over the trading days, like the fees a fund takes out of
+// its assets. Every price of a day is scaled by the same factor, so the daily
+// price ranges are kept.
+type ExpenseRatioSource struct {
+	Source  DataSource
+	Percent float64
+}
+
+func (es *ExpenseRatioSource) HistoricalData(symbol, fromDate, toDate string) (*NASDAQHistoricalAPIResponse, error) {
+	nd, err := es.Source.HistoricalData(symbol, fromDate, toDate)
+	if err != nil {
+		return nil, err
+	}
+
+	reduced := *nd
+	rows := nd.Data.TradesTable.Rows
+	reduced.Data.TradesTable.Rows = make([]*TradingData, len(rows))
+
+	dailyDrag := 1 - es.Percent/100/tradingDaysPerYear
+	factor := 1.0
+
+	// Rows are newest first
+	for i := len(rows) - 1; i >= 0; i-- {
+		if i < len(rows)-1 {
+			factor *= dailyDrag
+		}
+		r := *rows[i]
+		r.Open = scaleUSD(r.Open, factor)
+		r.High = scaleUSD(r.High, factor)
+		r.Low = scaleUSD(r.Low, factor)
+		r.Close = scaleUSD(r.Close, factor)
+		reduced.Data.TradesTable.Rows[i] = &r
+	}
+
+	return &reduced, nil
+}
+
+// scaleUSD returns the price multiplied by factor, or the price unchanged if
+// it can't be parsed.
+func scaleUSD(usd string, factor float64) string {
+	v, err := ParseUSD(usd)
+	if err != nil {
+		return usd
+	}
+	return strconv.FormatFloat(v*factor, 'f', -1, 64)
+}
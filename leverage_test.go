@@ -0,0 +1,69 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestExpenseRatioReducesValueOverAYear(t *testing.T) {
+	source := testSource{"ETF": weekdayRows("2020-01-01", "2020-12-31", flatPrice(100))}
+
+	// A single purchase held until valued at the end of the year
+	opts := Options{Source: source, AsOf: ISODateToTime("2020-12-31")}
+
+	plain := NewDCA("ETF", "2020-01-01", "2020-12-31", Yearly, 1000, opts)
+	reduced := NewDCA("ETF", "2020-01-01", "2020-12-31", Yearly, 1000, opts.withExpenseRatio(0.2))
+
+	if plain.TotalInvested != reduced.TotalInvested {
+		t.Fatalf("invested %v with the expense ratio, want %v", reduced.TotalInvested, plain.TotalInvested)
+	}
+	ratio := reduced.TotalReturn / plain.TotalReturn
+	if math.Abs(ratio-0.998) > 0.0005 {
+		t.Errorf("value is %.5f of the value without the expense ratio, want about 0.998", ratio)
+	}
+}
+
+func TestExpenseRatioKeepsDailyRanges(t *testing.T) {
+	rows := weekdayRows("2020-01-01", "2020-01-31", flatPrice(100))
+	for _, r := range rows {
+		r.Low, r.High = "$90.00", "$110.00"
+	}
+
+	es := &ExpenseRatioSource{Source: testSource{"ETF": rows}, Percent: 0.2}
+	nd, err := es.HistoricalData("ETF", "2020-01-01", "2020-01-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range nd.Data.TradesTable.Rows {
+		low := r.WeightedPrice(PriceWeights{Low: 1})
+		high := r.WeightedPrice(PriceWeights{High: 1})
+		if high-low < 19.9 {
+			t.Errorf("range on %s is %.2f - %.2f, want about 90 - 110", r.Date, low, high)
+		}
+	}
+}
+
+func TestLeveragedSourceDoublesDailyMoves(t *testing.T) {
+	source := testSource{"QQQ": weekdayRows("2020-01-01", "2020-01-31", alternatingPrice(5))}
+
+	ls := &LeveragedSource{Source: source, Leverage: 2}
+	nd, err := ls.HistoricalData("QQQ", "2020-01-01", "2020-01-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig, _ := source.HistoricalData("QQQ", "2020-01-01", "2020-01-31")
+
+	rows, origRows := nd.Data.TradesTable.Rows, orig.Data.TradesTable.Rows
+	if rows[len(rows)-1].AvgPrice() != origRows[len(origRows)-1].AvgPrice() {
+		t.Errorf("starts at %v, want the original %v", rows[len(rows)-1].AvgPrice(), origRows[len(origRows)-1].AvgPrice())
+	}
+	// Rows are newest first
+	for i := len(rows) - 2; i >= 0; i-- {
+		move := rows[i].AvgPrice()/rows[i+1].AvgPrice() - 1
+		origMove := origRows[i].AvgPrice()/origRows[i+1].AvgPrice() - 1
+		if math.Abs(move-2*origMove) > 1e-9 {
+			t.Errorf("moved %.4f on %s, want twice %.4f", move, rows[i].Date, origMove)
+		}
+	}
+}
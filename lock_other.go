@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+// lockFile doesn't lock on platforms without flock.
+func lockFile(file string) (func(), error) {
+	return func() {}, nil
+}
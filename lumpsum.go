@@ -0,0 +1,33 @@
+package main
+
+import "math"
+
+// LumpSum returns what the position would be worth at the end had its total
+// investment been made at once on the start date instead.
+func (d *DCA) LumpSum() float64 {
+	if d.prices == nil || len(d.prices.Data.TradesTable.Rows) == 0 {
+		return d.TotalReturn
+	}
+
+	price := d.prices.PriceCloseToDate(d.From)
+	if price <= 0 {
+		return d.TotalReturn
+	}
+
+	return d.TotalInvested / price * d.LastPrice
+}
+
+// LumpSumVerdict compares the DCA to investing the same total at once on the
+// start date, e.g. "DCA underperformed lump-sum by 3.21 %". Both are compared
+// by their simple PNL, whatever the --pnl-basis, as a lump-sum has a single
+// contribution.
+func (dp *DCAPortfolio) LumpSumVerdict() string {
+	diff := SimplePNLOf(dp.TotalInvested, dp.TotalReturn) - dp.LumpSumPNL
+	switch {
+	case math.Abs(diff) < 0.005:
+		return "DCA matched lump-sum"
+	case diff < 0:
+		return printer.Sprintf("DCA underperformed lump-sum by %.02f %%", -diff)
+	}
+	return printer.Sprintf("DCA outperformed lump-sum by %.02f %%", diff)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLumpSumWinsInARisingMarket(t *testing.T) {
+	// From 100 to about 200 over the year
+	source := testSource{"X": weekdayRows("2020-01-01", "2020-12-31", func(t time.Time) float64 { return 100 + 100*float64(t.YearDay()-1)/365 })}
+
+	dp := NewDCAPortfolio([]SymbolSpec{{Symbol: "X"}}, "2020-01-01", "2020-12-31", Monthly, 100, Options{Source: source})
+
+	d := dp.Positions[0]
+	if want := 1200 / 100 * d.LastPrice; math.Abs(dp.LumpSumReturn-want) > 1e-9 {
+		t.Errorf("lump-sum return %v, want all 1200 bought at 100 worth %v", dp.LumpSumReturn, want)
+	}
+	pnl := SimplePNLOf(dp.TotalInvested, dp.TotalReturn)
+	if dp.LumpSumPNL <= pnl {
+		t.Fatalf("lump-sum PNL %.2f %%, want more than the DCA's %.2f %%", dp.LumpSumPNL, pnl)
+	}
+	if got, want := dp.LumpSumVerdict(), printer.Sprintf("DCA underperformed lump-sum by %.02f %%", dp.LumpSumPNL-pnl); got != want {
+		t.Errorf("verdict %q, want %q", got, want)
+	}
+}
+
+func TestLumpSumVerdicts(t *testing.T) {
+	for _, tc := range []struct {
+		price func(time.Time) float64
+		want  string
+	}{
+		{func(t time.Time) float64 { return 200 - float64(t.YearDay())/4 }, "DCA outperformed lump-sum by "},
+		{flatPrice(100), "DCA matched lump-sum"},
+	} {
+		source := testSource{"X": weekdayRows("2020-01-01", "2020-12-31", tc.price)}
+		dp := NewDCAPortfolio([]SymbolSpec{{Symbol: "X"}}, "2020-01-01", "2020-12-31", Monthly, 100, Options{Source: source})
+		if got := dp.LumpSumVerdict(); !strings.HasPrefix(got, tc.want) {
+			t.Errorf("verdict %q, want %q", got, tc.want)
+		}
+	}
+}
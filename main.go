@@ -3,11 +3,16 @@ package main
 import (
 	"compress/gzip"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math"
+	"math/rand"
 	"net/http"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -15,13 +20,76 @@ import (
 	"github.com/spf13/pflag"
 	"golang.org/x/text/language"
 	"golang.org/x/text/message"
+	"golang.org/x/text/number"
 )
 
 var (
 	printer = message.NewPrinter(language.English)
+	// Number of decimals monetary values are printed with.
+	precision = 2
+	// Now returns the current time Today is based on, pinned by --today to
+	// make runs deterministic. Waits and timestamps use the real time.
+	Now = time.Now
+	// Whether Dump indents its JSON output.
+	prettyJSON = true
+	// Whether positions' transactions are included in JSON output.
+	includeTransactions = false
+	// The summary warns when the portfolio is down more than this many
+	// percent from its high, 0 to never warn.
+	drawdownAlert = 0.0
+	// Whether the benchmark's max drawdown is compared to the portfolio's.
+	compareDrawdowns = false
+	// Whether positions print the period asked for and the dates of their
+	// first and last purchase.
+	showEffectiveDates = false
+	// Symbol monetary values are printed with, no currency conversion is
+	// done.
+	currencySymbol = "$"
+	// Where results are written, stdout unless --output is given.
+	output io.Writer = os.Stdout
+	// Whether the start of every API response is logged.
+	verbose = false
 )
 
+// Today returns the current date as YYYY-MM-DD.
+func Today() string {
+	return Now().Format("2006-01-02")
+}
+
+// Money formats v as a monetary amount with the configured currency symbol and
+// precision, grouping thousands the way the printer's language does, e.g.
+// "$1,234,567.00".
+func Money(v float64) string {
+	if v < 0 {
+		return "-" + Money(-v)
+	}
+	return currencySymbol + printer.Sprint(number.Decimal(v, number.Scale(precision)))
+}
+
+// signedMoney formats an amount like Money with a plus sign when positive.
+func signedMoney(amount float64) string {
+	if amount > 0 {
+		return "+" + Money(amount)
+	}
+	return Money(amount)
+}
+
 func main() {
+	defer exitOnPanic()
+
+	if len(os.Args) > 1 && os.Args[1] == "batch" {
+		batchMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "fetch" {
+		fetchMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "prices" {
+		pricesMain(os.Args[2:])
+		return
+	}
+
 	symbols := pflag.StringSliceP("symbols", "s", []string{
 		"AAPL",
 		"MSFT",
@@ -30,14 +98,527 @@ func main() {
 		"META",
 		"AMD",
 		"GOOG",
-	}, "Symbols / Tickers to DCA into")
+	}, "Symbols / Tickers to DCA into, optionally as SYMBOL:frequency=weekly:amount=100 to override the schedule")
 	fromDate := pflag.StringP("from", "f", "2008-01-01", "Start DCA:ing from this date")
-	toDate := pflag.StringP("to", "t", time.Now().Format("2006-01-02"), "Stop DCA:ing at this date")
+	toDate := pflag.StringP("to", "t", Today(), "Stop DCA:ing at this date")
 	monthlyAmount := pflag.Float64P("amount", "a", 500.00, "Amount to invest every month")
+	account := pflag.String("account", "", "Account label for the symbols not given one with SYMBOL:account=label")
+	frequency := pflag.String("frequency", "monthly", "How often to invest the amount: daily, weekly, biweekly, monthly, quarterly or yearly")
+	schedule := pflag.String("schedule", "", "Days of the month to invest the amount on instead of a --frequency, e.g. 1,15 for semi-monthly")
+	biweeklyAnchor := pflag.String("biweekly-anchor", "", "Weekday biweekly purchases are made on, e.g. friday, defaults to the start date's")
+	minPrice := pflag.Float64("min-price", 0, "Skip trading days with an average price below this (e.g. 0.01)")
+	carrySkipped := pflag.Bool("carry-skipped", false, "Add purchases skipped due to bad prices to the next purchase")
+	benchmark := pflag.StringP("benchmark", "b", "", "Symbol / Ticker to compare the portfolio against, or several with weights like \"60% SPY, 40% AGG\"")
+	pflag.BoolVar(&compareDrawdowns, "benchmark-drawdown-compare", false, "Compare the max drawdown of the portfolio to the benchmark's")
+	benchmarkRebased := pflag.Bool("benchmark-rebased", false, "Output the portfolio's and the benchmark's daily values rebased to 100 at the start instead, as CSV or JSON")
+	benchmarkFile := pflag.String("benchmark-file", "", "CSV file with date and price columns to compare the portfolio against")
+	benchmarkContributionsMatch := pflag.Bool("benchmark-contributions-match", false, "Invest into the benchmark what the portfolio actually invested on the same dates, e.g. leaving out symbols before their inception")
+	benchmarkNone := pflag.Bool("benchmark-none", false, "Don't compare against a benchmark, overriding --benchmark and --benchmark-file, e.g. when set in an alias")
+	benchmarkLeverage := pflag.Float64("benchmark-leverage", 1, "Compare against a synthetic daily leveraged benchmark, e.g. 3 for 3x")
+	benchmarkDecay := pflag.Float64("benchmark-decay", 0, "Annual cost in percent deducted from the leveraged benchmark")
+	dataDir := pflag.String("data-dir", "", "Read trading data from <SYMBOL>.csv files in this directory instead of the NASDAQ API")
+	interpolate := pflag.Bool("interpolate", false, "Interpolate prices between trading days instead of using the next trading day")
+	strictDates := pflag.Bool("strict-dates", false, "Fail if a purchase can't be made on a trading day within --strict-dates-tolerance days")
+	strictDatesTolerance := pflag.Int("strict-dates-tolerance", 4, "Days after a scheduled purchase its trading day may be with --strict-dates")
+	interpolateWindow := pflag.Int("interpolate-window", 7, "Max days between trading days to interpolate across")
+	pflag.IntVar(&precision, "precision", precision, "Number of decimals to print monetary values with")
+	executionLag := pflag.Int("execution-lag", 0, "Execute purchases this many trading days after they're scheduled")
+	roundToCents := pflag.Bool("round-to-cents", false, "Invest a whole number of cents on every purchase")
+	prorate := pflag.Bool("prorate", false, "Make a final prorated purchase for the partial period before the end date")
+	pflag.StringVar(&currencySymbol, "currency-symbol", "$", "Symbol to print monetary values with, e.g. € or kr")
+	locale := pflag.String("locale", "en", "Language tag used to format numbers, e.g. de or sv-SE")
+	today := pflag.String("today", "", "Run as if today was this date, e.g. for reproducible output")
+	asOf := pflag.String("as-of", "", "Value the portfolio on this date instead of the end date")
+	noCache := pflag.Bool("no-cache", false, "Always fetch fresh data from the NASDAQ API, still updating the cache")
+	revalidate := pflag.Bool("revalidate", false, "Check cached data is up to date with the NASDAQ API, only downloading it again if it changed")
+	failOnEmpty := pflag.Bool("fail-on-empty", true, "Fail when the NASDAQ API returns no trading data for a symbol")
+	refreshEndingToday := pflag.Bool("refresh-ending-today", true, "Refresh cached data ending today, or fetched before the day it ends was over")
+	sinceLastRun := pflag.Bool("since-last-run", false, "Keep one growing cache file per symbol and start date, only fetching the trading days since the last run")
+	priceBasisFlag := pflag.String("price-basis", "ohlc", "Price purchases are made at: ohlc, typical, weighted-close, median, close or open,high,low,close weights like 0,1,1,2")
+	pnlBasisFlag := pflag.String("pnl-basis", "simple", "Basis of the PNL: simple (total return over total invested), money-weighted (IRR) or time-weighted")
+	persistRaw := pflag.Bool("persist-raw", false, "Also keep the API responses as received in <cache file>.raw.json files, to be able to parse them again later")
+	memoryCacheSize := pflag.Int("memory-cache-size", 64, "Number of fetched responses to keep in memory")
+	format := pflag.String("format", "text", "Output format: text or json")
+	pflag.BoolVar(&prettyJSON, "pretty", true, "Indent JSON output, use --pretty=false for compact single line JSON")
+	pflag.BoolVar(&includeTransactions, "include-transactions", false, "Include every position's purchases in JSON output")
+	pflag.BoolVar(&verbose, "verbose", false, "Log the start of every NASDAQ API response")
+	pflag.BoolVar(&showEffectiveDates, "show-effective-dates", false, "Print the period asked for and the trading days of the first and last purchase of every position")
+	outputFile := pflag.StringP("output", "O", "", "Write the results to this file instead of stdout, creating its directory if needed")
+	compareSymbols := pflag.Bool("compare-symbols", false, "Rank the symbols by DCA:ing into each of them on its own instead of as a portfolio")
+	correlations := pflag.Bool("correlations", false, "Output the correlation matrix of the symbols' daily returns instead, as CSV or JSON")
+	randomFills := pflag.Bool("random-fills", false, "Buy at a random price between the day's low and high instead of the --price-basis")
+	fillSeed := pflag.Int64("fill-seed", 1, "Seed for the --random-fills, the same seed gives the same fills")
+	fillRuns := pflag.Int("fill-runs", 1, "Repeat the --random-fills with this many seeds from --fill-seed on and report the spread of the outcomes")
+	startOffsets := pflag.Int("start-offsets", 0, "Compare runs with the start date shifted by up to this many months earlier and later")
+	plot := pflag.String("plot", "", "Write an SVG chart of the portfolio's value over time to this file")
+	savingsAPY := pflag.Float64("savings-apy", 0, "Compare the portfolio to keeping its contributions in a savings account at this APY in percent")
+	bondYield := pflag.Float64("bond-yield", 0, "Compare the portfolio to buying bonds yielding this many percent a year with its contributions, coupons reinvested")
+	cashflowsCSV := pflag.String("cashflows-csv", "", "Write the portfolio's dated cash flows to this CSV file, for a spreadsheet's XIRR")
+	summaryOnly := pflag.Bool("summary-only", false, "Only output the portfolio totals, without the positions")
+	pflag.Float64Var(&drawdownAlert, "drawdown-alert", 0, "Warn in the summary when the portfolio is currently down more than this many percent from its high")
+	validateSymbols := pflag.Bool("validate-symbols", false, "Check every symbol has trading data before the run, failing if any doesn't")
+	continueOnError := pflag.Bool("continue-on-error", false, "Leave out the symbols --validate-symbols finds without trading data instead of failing")
+	maxSymbols := pflag.Int("max-symbols", 500, "Fail instead of running more than this many symbols")
+	fromIndex := pflag.String("symbols-from-index", "", "DCA into the constituents of an index (nasdaq100 or dow30), added to any --symbols given")
+	weighting := pflag.String("weighting", EqualWeighting, "How to split the amount across symbols: equal, volatility, marketcap or custom --weights")
+	customWeights := pflag.Float64Slice("weights", nil, "Share of the amount for each symbol in order, e.g. 0.5,0.3,0.2, summing up to 1")
+	normalizeWeights := pflag.Bool("weights-normalize", false, "Rescale the --weights to sum up to 1 instead of failing if they don't")
+	marketCapFile := pflag.String("market-caps", "", "CSV file with symbol and market cap columns, for --weighting marketcap")
+	contributionsFile := pflag.String("contributions-file", "", "CSV file with date and amount columns of the exact contributions to invest, instead of a schedule")
+	expenseRatio := pflag.Float64("expense-ratio", 0, "Annual expense ratio in percent, e.g. 0.2, dragging on the value of every symbol unless its spec sets expense-ratio")
+	dividendsFile := pflag.String("dividends-file", "", "CSV file with symbol, payment date and dividend per unit columns of dividends to reinvest")
+	feePercent := pflag.Float64("fee-percent", 0, "Percentage of every buy and rebalancing sell charged as a fee")
+	dripFeePercent := pflag.Float64("drip-fee-percent", 0, "Percentage of each reinvested dividend charged as a fee")
+	holdingsFile := pflag.String("holdings-file", "", "CSV file with symbol, units and cost basis columns of existing holdings to start DCA:ing from")
+	rebalanceThreshold := pflag.Float64("rebalance-threshold", 0, "Rebalance when a position drifts this many percentage points from its target weight")
+	allowDuplicates := pflag.Bool("allow-duplicates", false, "Invest into symbols given more than once for each time they're given")
+	symbolMap := pflag.String("symbol-map", "", "CSV file mapping CUSIP / ISIN identifiers to tickers")
+	validate := pflag.String("validate", "", "Validate a cached JSON file and exit")
+	marketName := pflag.String("market", "us", "NASDAQ market to fetch data from: us or nordic (tickers are instrument IDs, e.g. SSE3966)")
+	income := pflag.Float64("income", 0, "Monthly income to invest --contribution-percent of, instead of a fixed --amount")
+	contributionPercent := pflag.Float64("contribution-percent", 10, "Percentage of --income to invest")
+	incomeGrowth := pflag.Float64("income-growth", 0, "Yearly percentage the purchase amounts grow by, e.g. with a growing --income")
+	dateLayout := pflag.String("date-layout", "", "Go time layout of the trading dates in API responses, e.g. 2006-01-02, if they're not in the market's usual layout")
+	pflag.StringVar(&nasdaqCookie, "cookie", "", "Cookie header to send with NASDAQ API requests, e.g. copied from a browser if requests get 403 Forbidden")
+	cookieFile := pflag.String("cookie-file", "", "Read the --cookie header from this file")
+	checkAPI := pflag.Bool("check-api", false, "Check that the NASDAQ API is reachable and exit")
+
+	pflag.CommandLine.Init(os.Args[0], pflag.ContinueOnError)
+	err := pflag.CommandLine.Parse(os.Args[1:])
+	if err == pflag.ErrHelp {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		pflag.Usage()
+		exit(ExitUsage)
+	}
+
+	handleInterrupts()
+
+	if *today != "" {
+		t := ISODateToTime(*today)
+		Now = func() time.Time { return t }
+		if !pflag.CommandLine.Changed("to") {
+			*toDate = Today()
+		}
+	}
+
+	tag, err := language.Parse(*locale)
+	if err != nil {
+		log.Printf("warning: invalid locale '%s', falling back to English: %s", *locale, err)
+		tag = language.English
+	}
+	printer = message.NewPrinter(tag)
+
+	if precision < 0 {
+		log.Panicf("precision must be 0 or more, got %d", precision)
+	}
+	if *format != "text" && *format != "json" {
+		log.Panicf("unknown output format '%s'", *format)
+	}
+	if *executionLag < 0 {
+		log.Panicf("execution lag must be 0 or more, got %d", *executionLag)
+	}
+
+	if *cookieFile != "" {
+		if nasdaqCookie != "" {
+			log.Panicf("--cookie and --cookie-file can't be used together")
+		}
+		data, err := os.ReadFile(*cookieFile)
+		if err != nil {
+			panic(err)
+		}
+		nasdaqCookie = strings.TrimSpace(string(data))
+	}
+
+	market, err = MarketByName(*marketName, *dateLayout)
+	if err != nil {
+		panic(err)
+	}
+
+	if *outputFile != "" {
+		f, err := CreateOutputFile(*outputFile)
+		if err != nil {
+			panic(err)
+		}
+		defer f.Close()
+		output = f
+	}
+
+	if *validate != "" {
+		issues, corrupt := ValidateCacheFile(*validate)
+		for _, vi := range issues {
+			fmt.Fprintln(output, vi)
+		}
+		if corrupt {
+			fmt.Fprintf(output, "%s is corrupt\n", *validate)
+			exit(ExitUsage)
+		}
+		fmt.Fprintf(output, "%s is valid\n", *validate)
+		return
+	}
+
+	if *checkAPI {
+		hc := CheckNASDAQAPI()
+		hc.Print()
+		if !hc.OK() {
+			exit(ExitAPI)
+		}
+		return
+	}
+
+	f, err := ParseFrequency(*frequency)
+	if err != nil {
+		panic(err)
+	}
+
+	err = ValidateDateRange(*fromDate, *toDate)
+	if err != nil {
+		panic(err)
+	}
+
+	priceBasis, err = ParsePriceWeights(*priceBasisFlag)
+	if err != nil {
+		panic(err)
+	}
+
+	pnlBasis, err = ParsePNLBasis(*pnlBasisFlag)
+	if err != nil {
+		panic(err)
+	}
+
+	var sch Schedule
+	if *schedule != "" {
+		sch, err = ParseSchedule(*schedule)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	if *income > 0 {
+		if pflag.CommandLine.Changed("amount") {
+			log.Panicf("--amount and --income can't be used together")
+		}
+		perYear := f.PerYear()
+		if sch != nil {
+			perYear = sch.PerYear()
+		}
+		*monthlyAmount = IncomeContribution(*income, *contributionPercent, perYear)
+	}
+
+	if *fromIndex != "" {
+		indexSymbols, err := IndexSymbols(*fromIndex)
+		if err != nil {
+			panic(err)
+		}
+		if pflag.CommandLine.Changed("symbols") {
+			*symbols = append(*symbols, indexSymbols...)
+		} else {
+			*symbols = indexSymbols
+		}
+	}
+
+	specs, err := ParseSymbolSpecs(*symbols)
+	if err != nil {
+		panic(err)
+	}
+	for i := range specs {
+		if specs[i].Account == "" {
+			specs[i].Account = *account
+		}
+	}
+
+	if *symbolMap != "" {
+		sr, err := LoadSymbolResolver(*symbolMap)
+		if err != nil {
+			panic(err)
+		}
+		for i := range specs {
+			specs[i].Symbol, err = sr.Resolve(specs[i].Symbol)
+			if err != nil {
+				panic(err)
+			}
+		}
+	}
+
+	if len(specs) > *maxSymbols {
+		log.Panicf("%d symbols to run, more than the limit of %d, raise it with --max-symbols %d to run them anyway", len(specs), *maxSymbols, len(specs))
+	}
+
+	opts := Options{
+		MinPrice:           *minPrice,
+		CarrySkipped:       *carrySkipped,
+		ExecutionLag:       *executionLag,
+		RoundToCents:       *roundToCents,
+		Prorate:            *prorate,
+		AllowDuplicates:    *allowDuplicates,
+		Weighting:          *weighting,
+		RebalanceThreshold: *rebalanceThreshold,
+		Benchmark:          strings.ToUpper(strings.TrimSpace(*benchmark)),
+		BenchmarkLeverage:  *benchmarkLeverage,
+		BenchmarkDecay:     *benchmarkDecay,
+		AmountGrowth:       *incomeGrowth / 100,
+		Schedule:           sch,
+		ExpenseRatio:       *expenseRatio,
+		RandomFills:        *randomFills,
+		FeePercent:         *feePercent,
+		FillSeed:           *fillSeed,
+
+		BenchmarkContributionsMatch: *benchmarkContributionsMatch,
+	}
+
+	var fileCache Cache = &FileCache{Dir: ".", PersistRaw: *persistRaw}
+	if *noCache {
+		fileCache = WriteOnlyCache{fileCache}
+	}
+	opts.Source = NASDAQSource{
+		Cache:             NewLRUCache(*memoryCacheSize, fileCache),
+		Revalidate:        *revalidate,
+		FailOnEmpty:       *failOnEmpty,
+		RefreshIncomplete: *refreshEndingToday,
+	}
+	if *sinceLastRun {
+		opts.Source = &IncrementalSource{
+			Source: NASDAQSource{Cache: NewLRUCache(0, nil), FailOnEmpty: *failOnEmpty},
+			Cache:  NewLRUCache(*memoryCacheSize, fileCache),
+		}
+	}
+
+	if len(*customWeights) > 0 {
+		if pflag.CommandLine.Changed("weighting") && opts.Weighting != CustomWeighting {
+			log.Panicf("--weights can't be used with --weighting %s", opts.Weighting)
+		}
+		opts.Weighting = CustomWeighting
+		opts.CustomWeights = *customWeights
+		opts.NormalizeWeights = *normalizeWeights
+	}
+
+	if *expenseRatio < 0 || *expenseRatio >= 100 {
+		log.Panicf("--expense-ratio must be between 0 and 100 percent, got %g", *expenseRatio)
+	}
+
+	if *contributionsFile != "" {
+		opts.Contributions, err = LoadContributions(*contributionsFile)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	if *dividendsFile != "" {
+		opts.Dividends, err = LoadDividends(*dividendsFile)
+		if err != nil {
+			panic(err)
+		}
+		opts.DripFeePercent = *dripFeePercent
+	}
+
+	opts.StrictDates = *strictDates
+	opts.StrictDatesTolerance = time.Duration(*strictDatesTolerance) * 24 * time.Hour
+
+	if *holdingsFile != "" {
+		opts.Holdings, err = LoadHoldings(*holdingsFile)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	if *marketCapFile != "" {
+		opts.MarketCaps, err = LoadMarketCaps(*marketCapFile)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	if *asOf != "" {
+		opts.AsOf = ISODateToTime(*asOf)
+		if opts.AsOf.Before(ISODateToTime(*fromDate)) || opts.AsOf.After(ISODateToTime(*toDate)) {
+			log.Panicf("as-of date %s is outside of %s - %s", *asOf, *fromDate, *toDate)
+		}
+	}
+
+	if *biweeklyAnchor != "" {
+		wd, err := ParseWeekday(*biweeklyAnchor)
+		if err != nil {
+			panic(err)
+		}
+		opts.BiweeklyAnchor = &wd
+	}
+
+	if *interpolate {
+		opts.InterpolateWindow = time.Duration(*interpolateWindow) * 24 * time.Hour
+	}
+
+	if *dataDir != "" {
+		opts.Source = &CSVDirSource{Dir: *dataDir}
+	}
+	if *benchmarkFile != "" {
+		cs := &CSVFileSource{File: *benchmarkFile}
+		opts.BenchmarkSource = cs
+		if opts.Benchmark == "" {
+			opts.Benchmark = cs.Symbol()
+		}
+	}
+	if *benchmarkNone {
+		opts.Benchmark = ""
+		opts.BenchmarkSource = nil
+		compareDrawdowns = false
+	}
+	if strings.ContainsAny(opts.Benchmark, ",%") {
+		if *benchmarkFile != "" {
+			log.Panicf("--benchmark-file can't be used with a benchmark of several symbols")
+		}
+		opts.BenchmarkBlend, err = ParseBenchmarkBlend(opts.Benchmark)
+		if err != nil {
+			panic(err)
+		}
+		opts.Benchmark = opts.BenchmarkBlend.Name
+	}
+	if compareDrawdowns && opts.Benchmark == "" {
+		log.Panicf("--benchmark-drawdown-compare needs a --benchmark or --benchmark-file")
+	}
+	if *benchmarkRebased && opts.Benchmark == "" {
+		log.Panicf("--benchmark-rebased needs a --benchmark or --benchmark-file")
+	}
+
+	if *validateSymbols {
+		checks := CheckSymbols(specs, *fromDate, *toDate, opts)
+		PrintSymbolChecks(checks)
+
+		bad := make(map[string]bool)
+		code := ExitOK
+		for _, sc := range checks {
+			if !sc.OK() {
+				bad[sc.Symbol] = true
+				code = ExitCode(sc.Err)
+			}
+		}
+		if len(bad) > 0 && (!*continueOnError || len(bad) == len(checks)) {
+			log.Printf("%d of %d symbols have no trading data or failed to fetch", len(bad), len(checks))
+			exit(code)
+		}
+
+		var good []SymbolSpec
+		for _, ss := range specs {
+			if !bad[ss.Symbol] {
+				good = append(good, ss)
+			}
+		}
+		specs = good
+	}
+
+	if *correlations {
+		var symbols []string
+		for _, ss := range DedupeSymbolSpecs(specs) {
+			symbols = append(symbols, ss.Symbol)
+		}
+		m, err := NewCorrelationMatrix(symbols, *fromDate, *toDate, opts)
+		if err != nil {
+			panic(err)
+		}
+		if *format == "json" {
+			Dump(m)
+		} else if err := m.WriteCSV(output); err != nil {
+			panic(err)
+		}
+		return
+	}
 
-	pflag.Parse()
+	if *compareSymbols {
+		cs := CompareSymbols(specs, *fromDate, *toDate, f, *monthlyAmount, opts)
+		if *format == "json" {
+			Dump(cs)
+		} else {
+			PrintSymbolComparison(cs)
+		}
+		return
+	}
+
+	if *fillRuns > 1 {
+		if !*randomFills {
+			log.Panicf("--fill-runs needs --random-fills")
+		}
+		fs := NewFillSimulation(specs, *fromDate, *toDate, f, *monthlyAmount, opts, *fillSeed, *fillRuns)
+		if *format == "json" {
+			Dump(fs)
+		} else {
+			fs.Print()
+		}
+		return
+	}
+
+	if *startOffsets > 0 {
+		st := NewStartTiming(specs, *fromDate, *toDate, f, *monthlyAmount, opts, *startOffsets)
+		if *format == "json" {
+			Dump(st)
+		} else {
+			st.Print()
+		}
+		return
+	}
+
+	dp, err := Run(RunOptions{
+		Symbols:   specs,
+		From:      *fromDate,
+		To:        *toDate,
+		Frequency: f,
+		Amount:    *monthlyAmount,
+		Options:   opts,
+	})
+	if err != nil {
+		panic(err)
+	}
 
-	NewDCAPortfolio(*symbols, *fromDate, *toDate, Monthly, *monthlyAmount)
+	if *plot != "" {
+		err = PlotSVG(*plot, "Portfolio: "+strings.Join(dp.Symbols(), ", "), dp.ValueSeries())
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	if *benchmarkRebased {
+		points := dp.RebasedSeries()
+		if *format == "json" {
+			Dump(points)
+		} else if err := WriteRebasedCSV(output, points); err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	if *savingsAPY < 0 {
+		log.Panicf("--savings-apy can't be negative, got %g", *savingsAPY)
+	}
+	if *savingsAPY > 0 {
+		dp.Savings = dp.CompareToSavings(*savingsAPY)
+	}
+	if *bondYield < 0 {
+		log.Panicf("--bond-yield can't be negative, got %g", *bondYield)
+	}
+	if *bondYield > 0 {
+		dp.Bonds = dp.CompareToBonds(*bondYield)
+	}
+
+	if *cashflowsCSV != "" {
+		err = WriteCashFlowsCSV(*cashflowsCSV, dp.CashFlows())
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	switch {
+	case *format == "text" && *summaryOnly:
+		dp.PrintSummary()
+	case *format == "text":
+		dp.Print()
+	case *format == "json" && *summaryOnly:
+		Dump(dp.Summary())
+	case *format == "json":
+		Dump(struct {
+			*DCAPortfolio
+			Holdings map[string]*Holding
+		}{dp, dp.Holdings()})
+	}
 }
 
 type Frequency int
@@ -46,10 +627,200 @@ const (
 	Daily Frequency = iota + 1
 	Weekly
 	Monthly
+	Quarterly
+	Yearly
+	Biweekly
 )
 
+func ParseFrequency(s string) (Frequency, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "daily":
+		return Daily, nil
+	case "weekly":
+		return Weekly, nil
+	case "biweekly":
+		return Biweekly, nil
+	case "monthly":
+		return Monthly, nil
+	case "quarterly":
+		return Quarterly, nil
+	case "yearly":
+		return Yearly, nil
+	}
+	return 0, fmt.Errorf("unknown frequency '%s'", s)
+}
+
+// PerYear returns the number of purchases per year.
+func (f Frequency) PerYear() float64 {
+	switch f {
+	case Daily:
+		return 365
+	case Weekly:
+		return 52
+	case Biweekly:
+		return 26
+	case Monthly:
+		return 12
+	case Quarterly:
+		return 4
+	case Yearly:
+		return 1
+	}
+	return 0
+}
+
+func (f Frequency) String() string {
+	switch f {
+	case Daily:
+		return "daily"
+	case Weekly:
+		return "weekly"
+	case Biweekly:
+		return "biweekly"
+	case Monthly:
+		return "monthly"
+	case Quarterly:
+		return "quarterly"
+	case Yearly:
+		return "yearly"
+	}
+	return fmt.Sprintf("Frequency(%d)", int(f))
+}
+
+// ParseWeekday parses the name of a weekday, e.g. friday or fri.
+func ParseWeekday(s string) (time.Weekday, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if s == name || s == name[:3] {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown weekday '%s'", s)
+}
+
+// IncomeContribution returns the amount to invest per purchase, with
+// perYear purchases a year, to invest percent of a monthly income.
+func IncomeContribution(income, percent, perYear float64) float64 {
+	return income * 12 / perYear * percent / 100
+}
+
+// Options tweaks how a DCA simulation treats the historical trading data.
+type Options struct {
+	// Trading days with an average price below MinPrice are treated as bad
+	// data and skipped.
+	MinPrice float64
+	// Purchases skipped because of a zero or negative price are added to the
+	// next purchase when CarrySkipped is set, otherwise they are dropped.
+	CarrySkipped bool
+	// Purchases on days without trading data normally use the next trading
+	// day's price. With InterpolateWindow set the price is interpolated
+	// between the surrounding trading days instead, which fails if they are
+	// further apart than InterpolateWindow.
+	InterpolateWindow time.Duration
+	// Purchases execute ExecutionLag trading days after they're scheduled,
+	// e.g. 1 to buy on the next trading day.
+	ExecutionLag int
+	// Biweekly purchases start on the first BiweeklyAnchor weekday on or
+	// after the start date, e.g. a payday, or on the start date if nil.
+	BiweeklyAnchor *time.Weekday
+	// With Schedule set purchases are made on its days of the month instead
+	// of at the purchase frequency.
+	Schedule Schedule
+	// With Contributions set purchases are made on their dates with their
+	// amounts instead, split across the symbols as the spend would be.
+	Contributions []Contribution
+	// With StrictDates set every purchase must find a trading day within
+	// StrictDatesTolerance after it's scheduled, instead of silently using
+	// the next trading day however far off.
+	StrictDates          bool
+	StrictDatesTolerance time.Duration
+	// With RoundToCents every purchase invests a whole number of cents. The
+	// fractions left over are added to the next purchase.
+	RoundToCents bool
+	// With Prorate set a final purchase is made on the end date, prorated
+	// by how much of the period since the last purchase it covers.
+	Prorate bool
+	// With AsOf set the positions are valued at the price on that date,
+	// counting only the purchases made up until then.
+	AsOf time.Time
+	// Symbols given more than once are only invested into once, unless
+	// AllowDuplicates is set, which gives them a larger share of the spend.
+	AllowDuplicates bool
+	// Weighting decides how the spend is split across symbols, equally by
+	// default.
+	Weighting string
+	// Market capitalization by symbol, used by the marketcap weighting.
+	MarketCaps map[string]float64
+	// Share of the spend for each symbol in order, used by the custom
+	// weighting. They must sum up to 1 unless NormalizeWeights is set, which
+	// rescales them to.
+	CustomWeights    []float64
+	NormalizeWeights bool
+	// With RebalanceThreshold set the positions are rebalanced back to their
+	// target weights whenever one of them drifts more than this many
+	// percentage points away from its target, checked on every purchase.
+	RebalanceThreshold float64
+	// Purchase amounts grow by AmountGrowth every year since the start, e.g.
+	// 0.03 to keep up with an income growing 3% a year.
+	AmountGrowth float64
+	// Dividends per unit by symbol, reinvested when paid less
+	// DripFeePercent of them.
+	Dividends      map[string][]Dividend
+	DripFeePercent float64
+	// Percentage of every buy and sell charged as a fee, deducted from the
+	// money invested or the proceeds.
+	FeePercent float64
+	// Existing holdings the DCAs into the same symbol and account start
+	// from, counted as invested at their cost basis on the start date.
+	// Holdings without a DCA are held on to until the end date.
+	Holdings []Account
+	// Annual expense ratio in percent dragging on the value of every
+	// position, unless its symbol spec overrides it.
+	ExpenseRatio float64
+	// With RandomFills set purchases execute at a random price between the
+	// day's low and high, picked reproducibly from FillSeed.
+	RandomFills bool
+	FillSeed    int64
+	// Source provides the trading data, defaults to the NASDAQ API.
+	Source DataSource
+	// When Benchmark is set the same contributions are also invested into
+	// the Benchmark symbol, fetched from BenchmarkSource if set.
+	Benchmark       string
+	BenchmarkSource DataSource
+	// With BenchmarkLeverage set the benchmark is a synthetic daily leveraged
+	// version of itself, see LeveragedSource.
+	BenchmarkLeverage float64
+	BenchmarkDecay    float64
+	// With BenchmarkBlend set the benchmark is a portfolio of its symbols
+	// instead of the Benchmark symbol.
+	BenchmarkBlend *BenchmarkBlend
+	// With BenchmarkContributionsMatch set the benchmark invests what the
+	// portfolio actually did on the same dates, e.g. leaving out the
+	// contributions to symbols before their inception, instead of the full
+	// spend on every purchase.
+	BenchmarkContributionsMatch bool
+}
+
+// withExpenseRatio returns the options with the trading data reduced by an
+// annual expense ratio of percent, spread over the trading days.
+func (o Options) withExpenseRatio(percent float64) Options {
+	if percent > 0 {
+		o.Source = &ExpenseRatioSource{Source: o.source(), Percent: percent}
+	}
+	return o
+}
+
+func (o Options) source() DataSource {
+	if o.Source == nil {
+		return NASDAQSource{}
+	}
+	return o.Source
+}
+
 type DCA struct {
 	Symbol            string
+	Account           string `json:",omitempty"`
 	Units             float64
 	InitialInvestment float64
 	PurchaseFrequency Frequency
@@ -57,60 +828,519 @@ type DCA struct {
 	TotalInvested     float64
 	TotalReturn       float64
 	PNL               float64
-	From              time.Time
-	To                time.Time
+	CAGR              float64
+	Volatility        float64
+	RoundingResidual  float64
+	LastPrice         float64
+	// Dividends received and reinvested, and the DRIP fees deducted from
+	// them.
+	Dividends float64 `json:",omitempty"`
+	DripFees  float64 `json:",omitempty"`
+	// The dividends of the last year in percent of the total invested.
+	YieldOnCost float64 `json:",omitempty"`
+	// Fees charged on buys and rebalancing sells.
+	Fees float64 `json:",omitempty"`
+	// Gains realized by rebalancing sells, see TaxLots.
+	RealizedGains *RealizedGains `json:",omitempty"`
+	// Lowest and highest prices paid by a purchase, i.e. the purchases that
+	// got the most and the fewest units per dollar.
+	BestPrice      float64
+	BestPriceDate  time.Time
+	WorstPrice     float64
+	WorstPriceDate time.Time
+	From           time.Time
+	To             time.Time
+	Transactions   []Transaction `json:"-"`
+
+	prices *NASDAQHistoricalAPIResponse
+	// The period asked for, before From moved to the symbol's first trading
+	// day.
+	requestedFrom, requestedTo time.Time
+}
+
+// MarshalJSON leaves out the transactions unless includeTransactions is set,
+// as there's one for every purchase.
+func (d DCA) MarshalJSON() ([]byte, error) {
+	type dca DCA
+	v := struct {
+		dca
+		Transactions []Transaction `json:",omitempty"`
+	}{dca: dca(d)}
+	if includeTransactions {
+		v.Transactions = d.Transactions
+	}
+	return json.Marshal(v)
+}
+
+type DCAPortfolio struct {
+	Name          string `json:",omitempty"`
+	Positions     []*DCA
+	TotalInvested float64
+	TotalReturn   float64
+	PNL           float64
+	CAGR          float64
+	// Annualized volatility of the portfolio's returns in percent.
+	Volatility float64
+	// How far in percent the portfolio is down from its high at the end.
+	CurrentDrawdown float64
+	// TotalReturn broken down into the money put in and the market growth
+	// on top of it.
+	Contributions float64
+	Growth        float64
+	// What investing the total at once on the start date would have
+	// returned instead.
+	LumpSumReturn float64
+	LumpSumPNL    float64
+	From          time.Time
+	To            time.Time
+	Rebalances    int
+	// Fees charged on the positions' buys and sells.
+	TotalFees float64 `json:",omitempty"`
+	// Gains realized by the positions' sells.
+	RealizedGains *RealizedGains `json:",omitempty"`
+	// Dividends received by the positions, and those of the last year in
+	// percent of the total invested.
+	TotalDividends float64 `json:",omitempty"`
+	YieldOnCost    float64 `json:",omitempty"`
+	Benchmark      *DCA
+	// A benchmark of several symbols, see BenchmarkBlend.
+	BlendedBenchmark *DCAPortfolio `json:",omitempty"`
+	// The contributions kept in a savings account instead.
+	Savings *FixedRateComparison `json:",omitempty"`
+	// The contributions invested into bonds at a fixed yield instead.
+	Bonds *FixedRateComparison `json:",omitempty"`
+}
+
+// NewDCAPortfolio splits spend across the symbols on every purchase, unless
+// a symbol overrides the amount it invests. Symbols can also override the
+// purchase frequency f.
+func NewDCAPortfolio(specs []SymbolSpec, fromDate, toDate string, f Frequency, spend float64, opts Options) *DCAPortfolio {
+	dp := new(DCAPortfolio)
+
+	if !opts.AllowDuplicates {
+		specs = DedupeSymbolSpecs(specs)
+	}
+
+	var shared []string
+	for _, ss := range specs {
+		if ss.Amount == 0 {
+			shared = append(shared, ss.Symbol)
+		}
+	}
+
+	weights, err := Weights(shared, fromDate, toDate, opts)
+	if err != nil {
+		panic(err)
+	}
+
+	frequencies := make([]Frequency, len(specs))
+	amounts := make([]float64, len(specs))
+
+	for i, ss := range specs {
+		frequencies[i] = f
+		if ss.Frequency != 0 {
+			frequencies[i] = ss.Frequency
+		}
+
+		amounts[i] = ss.Amount
+		if ss.Amount == 0 {
+			amounts[i] = spend * weights[0]
+			weights = weights[1:]
+		}
+	}
+
+	var runs []*dcaRun
+	for i, ss := range specs {
+		from, to := fromDate, toDate
+		if ss.From != "" {
+			from = ss.From
+		}
+		if ss.To != "" {
+			to = ss.To
+		}
+
+		er := opts.ExpenseRatio
+		if ss.ExpenseRatio != 0 {
+			er = ss.ExpenseRatio
+		}
+
+		r := newDCARun(ss.Symbol, from, to, frequencies[i], amounts[i], opts.withExpenseRatio(er))
+		if spend > 0 {
+			r.share = amounts[i] / spend
+		}
+		if h, ok := opts.holding(ss.Symbol, ss.Account); ok {
+			r.seed(h)
+		}
+		r.d.Account = ss.Account
+		runs = append(runs, r)
+	}
+
+	if opts.RebalanceThreshold > 0 {
+		// Target the share of the contributions each position gets
+		targets := make([]float64, len(specs))
+		for i := range specs {
+			targets[i] = amounts[i] * frequencies[i].PerYear()
+		}
+
+		dp.Rebalances = runRebalanced(runs, targets, opts.RebalanceThreshold/100)
+	} else {
+		for _, r := range runs {
+			for r.pending() {
+				r.purchase()
+			}
+		}
+	}
+
+	// Existing holdings not DCA:ed into are held on to
+	for _, h := range opts.Holdings {
+		dcaed := false
+		for _, ss := range specs {
+			dcaed = dcaed || (ss.Symbol == h.Symbol && ss.Account == h.Name)
+		}
+		if !dcaed {
+			r := newDCARun(h.Symbol, fromDate, toDate, f, 0, opts.withExpenseRatio(opts.ExpenseRatio))
+			r.seed(h)
+			r.d.Account = h.Name
+			runs = append(runs, r)
+		}
+	}
+
+	for _, r := range runs {
+		dp.Positions = append(dp.Positions, r.finish())
+	}
+
+	dp.total()
+
+	if opts.Benchmark != "" {
+		bopts := opts
+		if opts.BenchmarkSource != nil {
+			bopts.Source = opts.BenchmarkSource
+		}
+		if opts.BenchmarkContributionsMatch {
+			bopts.Contributions = dp.contributions()
+			bopts.Schedule = nil
+		}
+		leveraged := opts.BenchmarkLeverage != 0 && opts.BenchmarkLeverage != 1
+		if leveraged {
+			bopts.Source = &LeveragedSource{
+				Source:      bopts.source(),
+				Leverage:    opts.BenchmarkLeverage,
+				AnnualDecay: opts.BenchmarkDecay,
+			}
+		}
+		if opts.BenchmarkBlend != nil {
+			dp.BlendedBenchmark = newBlendedBenchmark(opts.BenchmarkBlend, fromDate, toDate, f, spend, bopts)
+			return dp
+		}
+		dp.Benchmark = NewDCA(opts.Benchmark, fromDate, toDate, f, spend, bopts)
+		if leveraged {
+			dp.Benchmark.Symbol = fmt.Sprintf("%s %gx", opts.Benchmark, opts.BenchmarkLeverage)
+		}
+	}
+
+	return dp
+}
+
+// total sums up the positions into the portfolio totals.
+func (dp *DCAPortfolio) total() {
+	dp.TotalInvested, dp.TotalReturn, dp.TotalFees = 0, 0, 0
+	dp.TotalDividends, dp.YieldOnCost = 0, 0
+	dp.RealizedGains = nil
+	dp.From, dp.To = time.Time{}, time.Time{}
+
+	for _, d := range dp.Positions {
+		dp.TotalInvested += d.TotalInvested
+		dp.TotalReturn += d.TotalReturn
+		dp.TotalFees += d.Fees
+		dp.TotalDividends += d.Dividends
+		if d.RealizedGains != nil {
+			if dp.RealizedGains == nil {
+				dp.RealizedGains = new(RealizedGains)
+			}
+			dp.RealizedGains.ShortTerm += d.RealizedGains.ShortTerm
+			dp.RealizedGains.LongTerm += d.RealizedGains.LongTerm
+		}
+		dp.YieldOnCost += d.YieldOnCost * d.TotalInvested
+
+		if dp.From.IsZero() || dp.From.After(d.From) {
+			dp.From = d.From
+		}
+		if dp.To.IsZero() || dp.To.Before(d.To) {
+			dp.To = d.To
+		}
+	}
+
+	series := dp.ValueSeries()
+	dp.PNL = PNL(dp.TotalInvested, dp.TotalReturn, series)
+	if dp.TotalInvested > 0 {
+		dp.YieldOnCost /= dp.TotalInvested
+	}
+	dp.CAGR = CAGR(series)
+	dp.Volatility = AnnualizedVolatility(series)
+	dp.CurrentDrawdown = CurrentDrawdown(series)
+	dp.Contributions = dp.TotalInvested
+	dp.Growth = dp.TotalReturn - dp.TotalInvested
+
+	dp.LumpSumReturn = 0
+	for _, d := range dp.Positions {
+		dp.LumpSumReturn += d.LumpSum()
+	}
+	dp.LumpSumPNL = SimplePNLOf(dp.TotalInvested, dp.LumpSumReturn)
+}
+
+// Merge combines portfolios into one holding all of their positions, e.g. a
+// taxable and a tax-advantaged account, with the totals recomputed across
+// them. Positions in the same symbol are kept as separate positions. The
+// benchmarks aren't merged, as they're only comparable to their own
+// portfolio.
+func Merge(portfolios ...*DCAPortfolio) *DCAPortfolio {
+	merged := new(DCAPortfolio)
+
+	for _, dp := range portfolios {
+		merged.Positions = append(merged.Positions, dp.Positions...)
+		merged.Rebalances += dp.Rebalances
+	}
+
+	merged.total()
+
+	return merged
+}
+
+// Accounts returns the account labels of the positions in the order they
+// first appear, without the empty label of positions not in an account.
+func (dp *DCAPortfolio) Accounts() []string {
+	seen := make(map[string]bool)
+	var accounts []string
+
+	for _, d := range dp.Positions {
+		if d.Account != "" && !seen[d.Account] {
+			seen[d.Account] = true
+			accounts = append(accounts, d.Account)
+		}
+	}
+
+	return accounts
+}
+
+// Account returns a portfolio of the positions held in account.
+func (dp *DCAPortfolio) Account(account string) *DCAPortfolio {
+	var positions []*DCA
+	for _, d := range dp.Positions {
+		if d.Account == account {
+			positions = append(positions, d)
+		}
+	}
+	return Merge(&DCAPortfolio{Positions: positions})
+}
+
+// Print prints the positions, grouped by account with the totals of each
+// account if they're held in any, followed by the portfolio totals.
+func (dp *DCAPortfolio) Print() {
+	accounts := dp.Accounts()
+	if len(accounts) == 0 {
+		for _, d := range dp.Positions {
+			d.Print()
+		}
+		dp.PrintSummary()
+		return
+	}
+
+	if len(accounts) < len(dp.Positions) {
+		for _, d := range dp.Positions {
+			if d.Account == "" {
+				accounts = append(accounts, "")
+				break
+			}
+		}
+	}
+
+	for _, account := range accounts {
+		ap := dp.Account(account)
+		for _, d := range ap.Positions {
+			d.Print()
+		}
+
+		name := account
+		if name == "" {
+			name = "(none)"
+		}
+		printer.Fprintf(output, "Account        : %s\n", name)
+		printer.Fprintf(output, "Total Invested : %s\n", Money(ap.TotalInvested))
+		printer.Fprintf(output, "Total Return   : %s\n", Money(ap.TotalReturn))
+		printer.Fprintf(output, "%s: %.02f %%\n\n", pnlLabel(), ap.PNL)
+	}
+
+	dp.PrintSummary()
+}
+
+func (dp *DCAPortfolio) Symbols() []string {
+	var symbols []string
+	for _, d := range dp.Positions {
+		symbols = append(symbols, d.Symbol)
+	}
+	return symbols
 }
 
-type DCAPortfolio struct {
-	Positions     []*DCA
-	TotalInvested float64
-	TotalReturn   float64
-	PNL           float64
+// PrintSummary prints the portfolio totals without the positions.
+func (dp *DCAPortfolio) PrintSummary() {
+	printer.Fprintf(output, "Portfolio      : %s\n", strings.Join(dp.Symbols(), ","))
+	printer.Fprintf(output, "Period         : %s - %s\n", dp.From.Format("2006-01-02"), dp.To.Format("2006-01-02"))
+	printer.Fprintf(output, "Total Invested : %s\n", Money(dp.TotalInvested))
+	printer.Fprintf(output, "Total Return   : %s\n", Money(dp.TotalReturn))
+	printer.Fprintf(output, "%s: %.02f %%\n", pnlLabel(), dp.PNL)
+	printer.Fprintf(output, "CAGR           : %.02f %%\n", dp.CAGR)
+	printer.Fprintf(output, "Volatility     : %.02f %%\n", dp.Volatility)
+	printer.Fprintf(output, "Contributions  : %s\n", Money(dp.Contributions))
+	printer.Fprintf(output, "Growth         : %s\n", Money(dp.Growth))
+	if dp.Rebalances > 0 {
+		printer.Fprintf(output, "Rebalances     : %d\n", dp.Rebalances)
+	}
+	if dp.TotalFees > 0 {
+		printer.Fprintf(output, "Total Fees     : %s\n", Money(dp.TotalFees))
+	}
+	if rg := dp.RealizedGains; rg != nil {
+		printer.Fprintf(output, "Realized Gains : %s short-term, %s long-term\n", Money(rg.ShortTerm), Money(rg.LongTerm))
+	}
+	if dp.TotalDividends > 0 {
+		printer.Fprintf(output, "Dividends      : %s\n", Money(dp.TotalDividends))
+		printer.Fprintf(output, "Yield on Cost  : %.02f %%\n", dp.YieldOnCost)
+	}
+	printer.Fprintf(output, "Lump-Sum PNL   : %.02f %%\n", dp.LumpSumPNL)
+	printer.Fprintf(output, "Verdict        : %s\n", dp.LumpSumVerdict())
+	if dp.Savings != nil {
+		printer.Fprintf(output, "Savings Return : %s at %.02f %% APY\n", Money(dp.Savings.TotalReturn), dp.Savings.Rate)
+		printer.Fprintf(output, "Savings PNL    : %.02f %%\n", dp.Savings.PNL)
+		printer.Fprintf(output, "vs Savings     : %s\n", signedMoney(dp.TotalReturn-dp.Savings.TotalReturn))
+	}
+	if dp.Bonds != nil {
+		printer.Fprintf(output, "Bonds Return   : %s at a %.02f %% yield\n", Money(dp.Bonds.TotalReturn), dp.Bonds.Rate)
+		printer.Fprintf(output, "Bonds PNL      : %.02f %%\n", dp.Bonds.PNL)
+		printer.Fprintf(output, "vs Bonds       : %s\n", signedMoney(dp.TotalReturn-dp.Bonds.TotalReturn))
+	}
+	if drawdownAlert > 0 && dp.CurrentDrawdown > drawdownAlert {
+		printer.Fprintf(output, "WARNING        : down %.02f %% from its high, more than the %.02f %% alert\n", dp.CurrentDrawdown, drawdownAlert)
+	}
+	printer.Fprintf(output, "\n")
+
+	if dp.Benchmark != nil || dp.BlendedBenchmark != nil {
+		dp.PrintBenchmark()
+	}
 }
 
-func NewDCAPortfolio(symbols []string, fromDate, toDate string, f Frequency, spend float64) {
-	dp := new(DCAPortfolio)
+type PortfolioSummary struct {
+	From            time.Time
+	To              time.Time
+	TotalInvested   float64
+	TotalReturn     float64
+	PNL             float64
+	CAGR            float64
+	Volatility      float64
+	CurrentDrawdown float64
+	Contributions   float64
+	Growth          float64
+	Savings         *FixedRateComparison `json:",omitempty"`
+	Bonds           *FixedRateComparison `json:",omitempty"`
+}
 
-	for _, symbol := range symbols {
-		s := spend / float64(len(symbols)) // Divide spend equally across all assets
-		d := NewDCA(symbol, fromDate, toDate, f, s)
-		dp.Positions = append(dp.Positions, d)
+func (dp *DCAPortfolio) Summary() PortfolioSummary {
+	return PortfolioSummary{
+		From:            dp.From,
+		To:              dp.To,
+		TotalInvested:   dp.TotalInvested,
+		TotalReturn:     dp.TotalReturn,
+		PNL:             dp.PNL,
+		CAGR:            dp.CAGR,
+		Volatility:      dp.Volatility,
+		CurrentDrawdown: dp.CurrentDrawdown,
+		Contributions:   dp.Contributions,
+		Growth:          dp.Growth,
+		Savings:         dp.Savings,
+		Bonds:           dp.Bonds,
 	}
+}
 
-	var allSymbols []string
-	var from, to time.Time
+type Holding struct {
+	Units float64
+	Price float64
+	Value float64
+}
 
-	for _, d := range dp.Positions {
-		dp.TotalInvested += d.TotalInvested
-		dp.TotalReturn += d.TotalReturn
+// Holdings breaks the portfolio down by symbol into the units held and their
+// value at the last price. The values sum up to TotalReturn.
+func (dp *DCAPortfolio) Holdings() map[string]*Holding {
+	holdings := make(map[string]*Holding)
 
-		if from.IsZero() || from.After(d.From) {
-			from = d.From
-		}
-		if to.IsZero() || to.Before(d.To) {
-			to = d.To
+	for _, d := range dp.Positions {
+		h, ok := holdings[d.Symbol]
+		if !ok {
+			h = &Holding{Price: d.LastPrice}
+			holdings[d.Symbol] = h
 		}
+		h.Units += d.Units
+		h.Value += d.TotalReturn
+	}
+
+	return holdings
+}
 
-		allSymbols = append(allSymbols, d.Symbol)
+func (dp *DCAPortfolio) PrintBenchmark() {
+	var b DCA
+	var series func() []ValuePoint
+	if bp := dp.BlendedBenchmark; bp != nil {
+		b = DCA{Symbol: bp.Name, From: bp.From, To: bp.To, TotalInvested: bp.TotalInvested, TotalReturn: bp.TotalReturn, PNL: bp.PNL}
+		series = bp.DailyValueSeries
+	} else {
+		b = *dp.Benchmark
+		series = dp.Benchmark.DailyValueSeries
+	}
+
+	printer.Fprintf(output, "Benchmark      : %s\n", b.Symbol)
+	printer.Fprintf(output, "Period         : %s - %s\n", b.From.Format("2006-01-02"), b.To.Format("2006-01-02"))
+	printer.Fprintf(output, "Total Invested : %s\n", Money(b.TotalInvested))
+	printer.Fprintf(output, "Total Return   : %s\n", Money(b.TotalReturn))
+	printer.Fprintf(output, "%s: %.02f %%\n", pnlLabel(), b.PNL)
+	printer.Fprintf(output, "vs Portfolio   : %+.02f %%\n", dp.PNL-b.PNL)
+	if compareDrawdowns {
+		bdd, pdd := MaxDrawdown(series()), MaxDrawdown(dp.DailyValueSeries())
+		printer.Fprintf(output, "Max Drawdown   : %.02f %% vs %.02f %% for the portfolio (%+.02f %%)\n", bdd, pdd, pdd-bdd)
+	}
+	printer.Fprintf(output, "\n")
+}
 
-		d.Print()
+func NewDCA(symbol, fromDate, toDate string, f Frequency, spend float64, opts Options) *DCA {
+	r := newDCARun(symbol, fromDate, toDate, f, spend, opts)
+	for r.pending() {
+		r.purchase()
 	}
+	return r.finish()
+}
 
-	dp.PNL = ((dp.TotalReturn / dp.TotalInvested) - 1) * 100
+// dcaRun simulates a DCA one purchase at a time, so that several of them can
+// be stepped through together.
+type dcaRun struct {
+	d    *DCA
+	nd   *NASDAQHistoricalAPIResponse
+	opts Options
 
-	printer.Printf("Portfolio      : %s\n", strings.Join(allSymbols, ","))
-	printer.Printf("Period         : %s - %s\n", from.Format("2006-01-02"), to.Format("2006-01-02"))
-	printer.Printf("Total Invested : $%.f\n", dp.TotalInvested)
-	printer.Printf("Total Return   : $%.f\n", dp.TotalReturn)
-	printer.Printf("PNL            : %.02f %%\n\n", dp.PNL)
+	at        time.Time
+	lastAt    time.Time
+	lastPrice float64
+	carry     float64
+	dividends int // Dividends reinvested so far
+	// Index of the next of the Contributions, and the share of each of
+	// them invested.
+	contribution int
+	share        float64
+	rng          *rand.Rand // Random fills, if enabled
 }
 
-func NewDCA(symbol, fromDate, toDate string, f Frequency, spend float64) *DCA {
+func newDCARun(symbol, fromDate, toDate string, f Frequency, spend float64, opts Options) *dcaRun {
+	err := ValidateDateRange(fromDate, toDate)
+	if err != nil {
+		panic(err)
+	}
 	from := ISODateToTime(fromDate)
 	to := ISODateToTime(toDate)
-	if from.After(to) {
-		log.Panicf("from date %s is after to date %s", from, to)
-	}
 
 	d := &DCA{
 		Symbol:            symbol,
@@ -118,7 +1348,17 @@ func NewDCA(symbol, fromDate, toDate string, f Frequency, spend float64) *DCA {
 		PurchaseAmount:    spend,
 	}
 
-	nd := GetNASDAQHistoricialDataCached(symbol, fromDate, toDate)
+	nd, err := opts.source().HistoricalData(symbol, fromDate, toDate)
+	if err != nil {
+		panic(err)
+	}
+	nd = nd.WithoutMissingPrices()
+	if opts.MinPrice > 0 {
+		nd = nd.WithoutPricesBelow(opts.MinPrice)
+	}
+	if len(nd.Data.TradesTable.Rows) == 0 {
+		panic(fmt.Errorf("%w available for %s", ErrNoTradingData, symbol))
+	}
 
 	firstAvailableTradeDate := NASDAQDateToTime(nd.Data.TradesTable.Rows[len(nd.Data.TradesTable.Rows)-1].Date)
 	if from.Before(firstAvailableTradeDate) {
@@ -127,57 +1367,362 @@ func NewDCA(symbol, fromDate, toDate string, f Frequency, spend float64) *DCA {
 
 	d.From = from
 	d.To = to
-	var lastPrice float64
+	d.requestedFrom = ISODateToTime(fromDate)
+	d.requestedTo = to
+	d.prices = nd
 
-	for at := from; at.Before(to); {
+	r := &dcaRun{d: d, nd: nd, opts: opts, at: from, share: 1}
+	if opts.RandomFills {
+		r.rng = fillRand(opts.FillSeed, symbol)
+	}
+	if opts.Contributions != nil {
+		r.warnContributionsAfter(to)
+		r.at = r.nextContribution(from)
+	} else if opts.Schedule != nil {
+		r.at = opts.Schedule.Next(from.AddDate(0, 0, -1))
+	} else if f == Biweekly && opts.BiweeklyAnchor != nil {
+		r.at = from.AddDate(0, 0, (int(*opts.BiweeklyAnchor)-int(from.Weekday())+7)%7)
+	}
 
-		price := nd.PriceCloseToDate(at)
-		// fmt.Printf("%s - date %s - price %.02f\n", symbol, at.Format("2006-01-02"), price)
+	return r
+}
 
-		d.Units += d.PurchaseAmount / price
-		d.TotalInvested += d.PurchaseAmount
+// seed starts the DCA from an existing holding, counted as invested at its
+// cost basis on the start date.
+func (r *dcaRun) seed(h Account) {
+	d := r.d
+	if h.Units <= 0 {
+		return
+	}
 
-		var next time.Time
-		if d.PurchaseFrequency == Monthly {
-			y := at.Year()
-			m := at.Month() + 1
-			if m == 13 {
-				m = 1
-				y++
-			}
-			next = time.Date(y, m, at.Day(), 0, 0, 0, 0, time.UTC)
-		} else if d.PurchaseFrequency == Weekly {
-			next = at.Add(7 * 24 * time.Hour)
-		} else {
-			next = at.Add(24 * time.Hour)
+	d.Units += h.Units
+	d.InitialInvestment += h.CostBasis
+	d.TotalInvested += h.CostBasis
+	d.Transactions = append(d.Transactions, Transaction{
+		Date:   d.From,
+		Price:  h.CostBasis / h.Units,
+		Amount: h.CostBasis,
+		Units:  h.Units,
+	})
+}
+
+func (r *dcaRun) priceAt(t time.Time) float64 {
+	if r.opts.InterpolateWindow > 0 {
+		price, err := r.nd.InterpolatedPrice(t, r.opts.InterpolateWindow)
+		if err != nil {
+			panic(err)
+		}
+		return price
+	}
+	return r.nd.PriceCloseToDate(t)
+}
+
+// tradedOn returns the trading day whose price priceAt uses for t, which
+// trades are recorded on, or t itself if prices are interpolated.
+func (r *dcaRun) tradedOn(t time.Time) time.Time {
+	if r.opts.InterpolateWindow > 0 {
+		return t
+	}
+	return NASDAQDateToTime(r.nd.Data.TradesTable.Rows[r.nd.tradingDayIndex(t)].Date)
+}
+
+// pending reports whether there are purchases left to make.
+func (r *dcaRun) pending() bool {
+	if !r.opts.AsOf.IsZero() && r.at.After(r.opts.AsOf) {
+		return false
+	}
+	if r.opts.Contributions != nil {
+		// Contributions on the end date are invested too
+		return r.contribution < len(r.opts.Contributions) && !r.at.After(r.d.To)
+	}
+	return r.at.Before(r.d.To)
+}
+
+// purchase makes the next scheduled purchase.
+func (r *dcaRun) purchase() {
+	d, at := r.d, r.at
+
+	r.reinvestDividends(at)
+	r.buy(at, r.purchaseAmount(at))
+
+	var next time.Time
+	if r.opts.Contributions != nil {
+		r.contribution++
+		next = r.nextContribution(at)
+	} else if r.opts.Schedule != nil {
+		next = r.opts.Schedule.Next(at)
+	} else if d.PurchaseFrequency == Monthly {
+		y := at.Year()
+		m := at.Month() + 1
+		if m == 13 {
+			m = 1
+			y++
+		}
+		next = time.Date(y, m, at.Day(), 0, 0, 0, 0, time.UTC)
+	} else if d.PurchaseFrequency == Quarterly {
+		next = addMonths(at, 3, d.From.Day())
+	} else if d.PurchaseFrequency == Yearly {
+		next = addMonths(at, 12, d.From.Day())
+	} else if d.PurchaseFrequency == Weekly {
+		next = at.Add(7 * 24 * time.Hour)
+	} else if d.PurchaseFrequency == Biweekly {
+		next = at.Add(14 * 24 * time.Hour)
+	} else {
+		next = at.Add(24 * time.Hour)
+	}
+
+	r.lastAt = at
+	r.at = next
+}
+
+// addMonths returns the date months after t on the given day of the month,
+// or on the last day of the month if it's shorter.
+func addMonths(t time.Time, months, day int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(months), 1, 0, 0, 0, 0, time.UTC)
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+func (r *dcaRun) buy(at time.Time, amount float64) {
+	d := r.d
+
+	executeAt := at
+	if r.opts.ExecutionLag > 0 {
+		executeAt = r.nd.LaggedTradingDay(at, r.opts.ExecutionLag)
+	}
+
+	if r.opts.StrictDates {
+		day := NASDAQDateToTime(r.nd.Data.TradesTable.Rows[r.nd.tradingDayIndex(at)].Date)
+		if day.Before(at) || day.Sub(at) > r.opts.StrictDatesTolerance {
+			log.Panicf("no %s trading day within %d days of the purchase on %s", d.Symbol, int(r.opts.StrictDatesTolerance.Hours()/24), at.Format("2006-01-02"))
+		}
+	}
+
+	tradedAt := r.tradedOn(executeAt)
+
+	var price float64
+	if r.rng != nil {
+		price = r.nd.Data.TradesTable.Rows[r.nd.tradingDayIndex(executeAt)].RandomFill(r.rng)
+	} else {
+		price = r.priceAt(executeAt)
+	}
+	// fmt.Printf("%s - date %s - price %.02f\n", symbol, at.Format("2006-01-02"), price)
+
+	amount += r.carry
+	r.carry = 0
+
+	if price > 0 {
+		if r.opts.RoundToCents {
+			amount += d.RoundingResidual
+			cents := math.Floor(amount*100 + 1e-6)
+			d.RoundingResidual = amount - cents/100
+			amount = cents / 100
 		}
+		fee := amount * r.opts.FeePercent / 100
+		units := (amount - fee) / price
+		d.Units += units
+		d.TotalInvested += amount
+		d.Fees += fee
+		d.Transactions = append(d.Transactions, Transaction{
+			Date:   tradedAt,
+			Price:  price,
+			Amount: amount,
+			Units:  units,
+			Fee:    fee,
+		})
+		if d.BestPriceDate.IsZero() || price < d.BestPrice {
+			d.BestPrice = price
+			d.BestPriceDate = tradedAt
+		}
+		if d.WorstPriceDate.IsZero() || price > d.WorstPrice {
+			d.WorstPrice = price
+			d.WorstPriceDate = tradedAt
+		}
+		r.lastPrice = price
+	} else {
+		log.Printf("warning: skipping %s purchase on %s, price %.2f is not positive", d.Symbol, at.Format("2006-01-02"), price)
+		if r.opts.CarrySkipped {
+			r.carry = amount
+		}
+	}
+}
+
+// prorate makes a final purchase on the end date for the part of the period
+// between the last purchase and the end date, e.g. half the purchase amount
+// if the end date is half way to the next purchase.
+func (r *dcaRun) prorate() {
+	if r.lastAt.IsZero() || !r.at.After(r.d.To) {
+		return
+	}
+
+	frac := float64(r.d.To.Sub(r.lastAt)) / float64(r.at.Sub(r.lastAt))
+	if frac <= 0 {
+		return
+	}
+
+	r.buy(r.d.To, r.purchaseAmount(r.lastAt)*frac)
+}
+
+// purchaseAmount returns the amount to invest in a purchase at t, grown by
+// AmountGrowth once for every full year since the start.
+func (r *dcaRun) purchaseAmount(t time.Time) float64 {
+	if r.opts.Contributions != nil {
+		return r.opts.Contributions[r.contribution].Amount * r.share
+	}
+	if r.opts.AmountGrowth == 0 {
+		return r.d.PurchaseAmount
+	}
+
+	years := 0
+	for !r.d.From.AddDate(years+1, 0, 0).After(t) {
+		years++
+	}
+
+	return r.d.PurchaseAmount * math.Pow(1+r.opts.AmountGrowth, float64(years))
+}
+
+// finish values the units bought and returns the result.
+func (r *dcaRun) finish() *DCA {
+	d := r.d
+
+	if r.opts.Prorate && r.opts.AsOf.IsZero() {
+		r.prorate()
+	}
+
+	if r.opts.AsOf.IsZero() {
+		r.reinvestDividends(d.To)
+	} else {
+		r.reinvestDividends(r.opts.AsOf)
+	}
 
-		at = next
-		lastPrice = price
+	lastPrice := r.lastPrice
+	if lastPrice == 0 && d.Units > 0 {
+		// Only holding units from before the start, value them at the
+		// latest price
+		lastPrice = r.nd.Data.TradesTable.Rows[0].AvgPrice()
+	}
+	if !r.opts.AsOf.IsZero() {
+		d.To = r.opts.AsOf
+		if d.From.After(d.To) {
+			// Valued before the first trading day, nothing was bought
+			d.From = d.To
+		}
+		lastPrice = r.priceAt(r.opts.AsOf)
 	}
 
+	d.LastPrice = lastPrice
 	d.TotalReturn += d.Units * lastPrice
-	d.PNL = ((d.TotalReturn / d.TotalInvested) - 1) * 100
+	series := d.ValueSeries()
+	d.PNL = PNL(d.TotalInvested, d.TotalReturn, series)
+	d.CAGR = CAGR(series)
+	d.Volatility = AnnualizedVolatility(series)
+	d.YieldOnCost = d.yieldOnCost(d.To)
+	if _, gains := d.TaxLots(); gains != (RealizedGains{}) {
+		d.RealizedGains = &gains
+	}
 
 	return d
 }
 
+// EffectivePeriod returns the trading days the first and last purchases of
+// new money were priced on, or false if there were none.
+func (d *DCA) EffectivePeriod() (first, last time.Time, ok bool) {
+	for _, t := range d.Transactions {
+		if t.Rebalance || t.Dividend || t.Amount <= 0 {
+			continue
+		}
+		day := t.Date
+		if d.prices != nil && len(d.prices.Data.TradesTable.Rows) > 0 {
+			day = NASDAQDateToTime(d.prices.Data.TradesTable.Rows[d.prices.tradingDayIndex(t.Date)].Date)
+		}
+		if !ok || day.Before(first) {
+			first = day
+		}
+		if !ok || day.After(last) {
+			last = day
+		}
+		ok = true
+	}
+	return first, last, ok
+}
+
+// CAGR returns the compound annual growth rate in percent of the money
+// invested in series, the annual internal rate of return of its
+// contributions, so that money invested late in the period isn't counted as
+// growing over all of it. Returns 0 if there's no period.
+func CAGR(series []ValuePoint) float64 {
+	rate, _, ok := moneyWeightedRate(series)
+	if !ok {
+		return 0
+	}
+	return rate * 100
+}
+
 func (d *DCA) Print() {
-	printer.Printf("Symbol         : %s\n", d.Symbol)
-	printer.Printf("Period         : %s - %s\n", d.From.Format("2006-01-02"), d.To.Format("2006-01-02"))
-	printer.Printf("Total Invested : $%.f\n", d.TotalInvested)
-	printer.Printf("Total Return   : $%.f\n", d.TotalReturn)
-	printer.Printf("PNL            : %.02f %%\n\n", d.PNL)
+	printer.Fprintf(output, "Symbol         : %s\n", d.Symbol)
+	if d.Account != "" {
+		printer.Fprintf(output, "Account        : %s\n", d.Account)
+	}
+	printer.Fprintf(output, "Period         : %s - %s\n", d.From.Format("2006-01-02"), d.To.Format("2006-01-02"))
+	if showEffectiveDates && !d.requestedFrom.IsZero() {
+		printer.Fprintf(output, "Requested      : %s - %s\n", d.requestedFrom.Format("2006-01-02"), d.requestedTo.Format("2006-01-02"))
+		if first, last, ok := d.EffectivePeriod(); ok {
+			printer.Fprintf(output, "Effective      : %s - %s\n", first.Format("2006-01-02"), last.Format("2006-01-02"))
+		}
+	}
+	printer.Fprintf(output, "Units          : %.4f\n", d.Units)
+	if d.InitialInvestment > 0 {
+		printer.Fprintf(output, "Initial Cost   : %s\n", Money(d.InitialInvestment))
+	}
+	printer.Fprintf(output, "Total Invested : %s\n", Money(d.TotalInvested))
+	printer.Fprintf(output, "Total Return   : %s\n", Money(d.TotalReturn))
+	printer.Fprintf(output, "%s: %.02f %%\n", pnlLabel(), d.PNL)
+	printer.Fprintf(output, "CAGR           : %.02f %%\n", d.CAGR)
+	printer.Fprintf(output, "Volatility     : %.02f %%\n", d.Volatility)
+	if d.Dividends > 0 {
+		printer.Fprintf(output, "Dividends      : %s\n", Money(d.Dividends))
+		printer.Fprintf(output, "Yield on Cost  : %.02f %%\n", d.YieldOnCost)
+	}
+	if d.DripFees > 0 {
+		printer.Fprintf(output, "DRIP Fees      : %s\n", Money(d.DripFees))
+	}
+	if d.Fees > 0 {
+		printer.Fprintf(output, "Fees           : %s\n", Money(d.Fees))
+	}
+	if rg := d.RealizedGains; rg != nil {
+		printer.Fprintf(output, "Realized Gains : %s short-term, %s long-term\n", Money(rg.ShortTerm), Money(rg.LongTerm))
+	}
+	if !d.BestPriceDate.IsZero() {
+		printer.Fprintf(output, "Best Purchase  : %s on %s\n", Money(d.BestPrice), d.BestPriceDate.Format("2006-01-02"))
+		printer.Fprintf(output, "Worst Purchase : %s on %s\n", Money(d.WorstPrice), d.WorstPriceDate.Format("2006-01-02"))
+	}
+	printer.Fprintf(output, "\n")
 }
 
-type Account struct {
-	Symbol string
-	Units  float64
+// CreateOutputFile creates or truncates file, creating its parent directories
+// if they don't exist.
+func CreateOutputFile(file string) (*os.File, error) {
+	err := os.MkdirAll(filepath.Dir(file), 0755)
+	if err != nil {
+		return nil, err
+	}
+	return os.Create(file)
 }
 
 func Dump(o interface{}) {
-	j, _ := json.MarshalIndent(o, "", "  ")
-	fmt.Println(string(j))
+	var j []byte
+	var err error
+	if prettyJSON {
+		j, err = json.MarshalIndent(o, "", "  ")
+	} else {
+		j, err = json.Marshal(o)
+	}
+	if err != nil {
+		panic(fmt.Errorf("could not write the results as JSON: %w", err))
+	}
+	fmt.Fprintln(output, string(j))
 }
 
 type NASDAQHistoricalAPIResponse struct {
@@ -187,7 +1732,28 @@ type NASDAQHistoricalAPIResponse struct {
 		TradesTable  struct {
 			Rows []*TradingData
 		} `json:"tradesTable"`
+		Extra map[string]json.RawMessage `json:"-"` // See UnmarshalJSON
+	}
+	// Validators for conditional requests and when the response was
+	// fetched, not part of the API response.
+	ETag         string    `json:",omitempty"`
+	LastModified string    `json:",omitempty"`
+	FetchedAt    time.Time `json:",omitempty"`
+	// Fields of the API response not modeled above, e.g. its status.
+	Extra map[string]json.RawMessage `json:"-"`
+
+	raw [][]byte // The response bodies as received, newest first, if fetched from the API
+}
+
+// MaybeIncomplete reports whether a response with trading data up until
+// toDate might be missing trading days, because it was fetched before the
+// end of that day. Responses fetched before FetchedAt was recorded are only
+// assumed to be incomplete if toDate is today or later.
+func (ndr *NASDAQHistoricalAPIResponse) MaybeIncomplete(toDate string) bool {
+	if toDate >= Today() {
+		return true
 	}
+	return !ndr.FetchedAt.IsZero() && ndr.FetchedAt.Format("2006-01-02") <= toDate
 }
 
 type TradingData struct {
@@ -199,6 +1765,23 @@ type TradingData struct {
 	Low    string
 }
 
+// ValidateDateRange checks that two YYYY-MM-DD dates are valid and that the
+// start date isn't after the end date.
+func ValidateDateRange(fromDate, toDate string) error {
+	from, err := time.Parse("2006-01-02", fromDate)
+	if err != nil {
+		return fmt.Errorf("invalid start date '%s', expected YYYY-MM-DD", fromDate)
+	}
+	to, err := time.Parse("2006-01-02", toDate)
+	if err != nil {
+		return fmt.Errorf("invalid end date '%s', expected YYYY-MM-DD", toDate)
+	}
+	if from.After(to) {
+		return fmt.Errorf("start date %s is after end date %s", fromDate, toDate)
+	}
+	return nil
+}
+
 func ISODateToTime(date string) time.Time {
 	t, err := time.Parse("2006-01-02", date)
 	if err != nil {
@@ -207,71 +1790,252 @@ func ISODateToTime(date string) time.Time {
 	return t
 }
 
+// Layout of the trading dates in NASDAQ API responses. Responses are
+// normalized to it when fetched, see NormalizeDates.
+const nasdaqDateLayout = "01/02/2006"
+
+// Other layouts trading dates have been seen in, tried in order when a date
+// doesn't match the expected layout.
+var nasdaqDateLayouts = []string{nasdaqDateLayout, "2006-01-02", "1/2/2006", "2006/01/02"}
+
+// ParseNASDAQDate parses a trading date using the layouts given, if any,
+// falling back to the known NASDAQ layouts.
+func ParseNASDAQDate(date string, layouts ...string) (time.Time, error) {
+	for _, layout := range append(layouts, nasdaqDateLayouts...) {
+		t, err := time.Parse(layout, strings.TrimSpace(date))
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized trading date format '%s'", date)
+}
+
+// NASDAQDateToTime parses a trading date of a normalized response.
 func NASDAQDateToTime(date string) time.Time {
-	t, err := time.Parse("01/02/2006", date)
+	t, err := ParseNASDAQDate(date)
 	if err != nil {
 		panic(err)
 	}
 	return t
 }
 
+// NormalizeDates rewrites the trading dates of all rows to nasdaqDateLayout,
+// parsing them with layout first if given. An error is returned for the first
+// date in an unrecognized format.
+func (ndr *NASDAQHistoricalAPIResponse) NormalizeDates(layout string) error {
+	var layouts []string
+	if layout != "" {
+		layouts = append(layouts, layout)
+	}
+
+	for _, r := range ndr.Data.TradesTable.Rows {
+		t, err := ParseNASDAQDate(r.Date, layouts...)
+		if err != nil {
+			return err
+		}
+		r.Date = t.Format(nasdaqDateLayout)
+	}
+
+	return nil
+}
+
+// AvgPrice averages the open, close, high and low prices as weighted by the
+// --price-basis, equally by default, ignoring any of them that are missing.
+// It returns 0 if all of them are missing.
 func (t *TradingData) AvgPrice() float64 {
-	return (USDStringToFloat(t.Open) +
-		USDStringToFloat(t.Close) +
-		USDStringToFloat(t.High) +
-		USDStringToFloat(t.Low)) / 4
+	return t.WeightedPrice(priceBasis)
+}
+
+// HasPrice reports whether at least one of the open, close, high and low
+// prices is present and valid.
+func (t *TradingData) HasPrice() bool {
+	for _, p := range []string{t.Open, t.Close, t.High, t.Low} {
+		if _, err := ParseUSD(p); err == nil {
+			return true
+		}
+	}
+	return false
 }
 
-func USDStringToFloat(usd string) float64 {
-	usd = strings.Replace(usd, "$", "", -1)
+// ErrMissingValue is returned when NASDAQ reports a placeholder such as "N/A"
+// instead of a number, which happens on thin trading days.
+var ErrMissingValue = errors.New("missing value")
+
+// Amounts with thousands separators, e.g. "1,234.56".
+var groupedThousands = regexp.MustCompile(`^-?[0-9]{1,3}(,[0-9]{3})+(\.[0-9]*)?$`)
+
+// ParseUSD parses a dollar amount such as "$1,234.56", returning
+// ErrMissingValue for placeholders and an error for anything that isn't a
+// finite number.
+func ParseUSD(usd string) (float64, error) {
+	usd = strings.TrimSpace(strings.Replace(usd, "$", "", -1))
+	if usd == "" || strings.EqualFold(usd, "N/A") {
+		return 0, ErrMissingValue
+	}
+	if groupedThousands.MatchString(usd) {
+		usd = strings.Replace(usd, ",", "", -1)
+	}
 	v, err := strconv.ParseFloat(usd, 64)
-	if err != nil {
-		log.Panicf("could not convert value '%s' to float", usd)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("could not convert value '%s' to float", usd)
 	}
-	return v
+	return v, nil
 }
 
 func (ndr *NASDAQHistoricalAPIResponse) PriceCloseToDate(d time.Time) float64 {
-	current := ndr.Data.TradesTable.Rows[0]
+	return ndr.Data.TradesTable.Rows[ndr.tradingDayIndex(d)].AvgPrice()
+}
 
-	for _, r := range ndr.Data.TradesTable.Rows {
+// LaggedTradingDay returns the date lag trading days after the first trading
+// day on or after d, or the last trading day if there aren't enough of them.
+func (ndr *NASDAQHistoricalAPIResponse) LaggedTradingDay(d time.Time, lag int) time.Time {
+	i := ndr.tradingDayIndex(d) - lag // Rows are newest first
+	if i < 0 {
+		i = 0
+	}
+	return NASDAQDateToTime(ndr.Data.TradesTable.Rows[i].Date)
+}
+
+// tradingDayIndex returns the index of the first trading day on or after d,
+// or of the last trading day if there's none.
+func (ndr *NASDAQHistoricalAPIResponse) tradingDayIndex(d time.Time) int {
+	current := 0
+
+	for i, r := range ndr.Data.TradesTable.Rows {
 		t := NASDAQDateToTime(r.Date)
 		if d.After(t) {
 			break
 		}
-		current = r
+		current = i
 	}
 
-	return current.AvgPrice()
+	return current
 }
 
-func GetNASDAQHistoricialDataCached(ticker, fromDate, toDate string) *NASDAQHistoricalAPIResponse {
-	file := fmt.Sprintf("./%s-%s-%s.json", ticker, fromDate, toDate)
-	_, err := os.Stat(file)
-	if err == nil {
-		data, err := os.ReadFile(file)
-		if err != nil {
-			panic(err)
+// InterpolatedPrice returns the average price on d, linearly interpolated
+// between the surrounding trading days if d isn't a trading day itself. It
+// fails if those trading days are more than maxGap apart.
+func (ndr *NASDAQHistoricalAPIResponse) InterpolatedPrice(d time.Time, maxGap time.Duration) (float64, error) {
+	gapErr := fmt.Errorf("cannot interpolate %s price on %s, no trading days within %d days",
+		ndr.Data.Symbol, d.Format("2006-01-02"), int(maxGap.Hours()/24))
+
+	var next *TradingData
+	var nextDate time.Time
+
+	for _, r := range ndr.Data.TradesTable.Rows {
+		t := NASDAQDateToTime(r.Date)
+		if t.Equal(d) {
+			return r.AvgPrice(), nil
 		}
 
-		ndr := new(NASDAQHistoricalAPIResponse)
-		err = json.Unmarshal(data, ndr)
-		if err != nil {
-			panic(err)
+		if t.Before(d) {
+			if next == nil {
+				// d is after the last trading day
+				if d.Sub(t) > maxGap {
+					return 0, gapErr
+				}
+				return r.AvgPrice(), nil
+			}
+			if nextDate.Sub(t) > maxGap {
+				return 0, gapErr
+			}
+
+			p0, p1 := r.AvgPrice(), next.AvgPrice()
+			frac := float64(d.Sub(t)) / float64(nextDate.Sub(t))
+
+			return p0 + (p1-p0)*frac, nil
 		}
 
-		return ndr
+		next, nextDate = r, t
 	}
 
-	ndr := CallNASDAQHistoricialAPI(ticker, fromDate, toDate)
+	// d is before the first trading day
+	if next == nil || nextDate.Sub(d) > maxGap {
+		return 0, gapErr
+	}
+	return next.AvgPrice(), nil
+}
 
-	if len(ndr.Data.TradesTable.Rows) > 0 {
-		j, err := json.MarshalIndent(ndr, "", "  ")
+// WithoutPricesBelow returns a copy of ndr without the trading days whose
+// average price is below min, logging a warning for each skipped day.
+func (ndr *NASDAQHistoricalAPIResponse) WithoutPricesBelow(min float64) *NASDAQHistoricalAPIResponse {
+	return ndr.filterRows(func(r *TradingData) bool {
+		if p := r.AvgPrice(); p < min {
+			log.Printf("warning: skipping %s on %s, price %.4f is below %.4f", ndr.Data.Symbol, r.Date, p, min)
+			return false
+		}
+		return true
+	})
+}
+
+// WithoutMissingPrices returns a copy of ndr without the trading days that
+// have no valid prices at all, logging a warning for each skipped day.
+func (ndr *NASDAQHistoricalAPIResponse) WithoutMissingPrices() *NASDAQHistoricalAPIResponse {
+	return ndr.filterRows(func(r *TradingData) bool {
+		if !r.HasPrice() {
+			log.Printf("warning: skipping %s on %s, no valid prices available", ndr.Data.Symbol, r.Date)
+			return false
+		}
+		return true
+	})
+}
+
+func (ndr *NASDAQHistoricalAPIResponse) filterRows(keep func(r *TradingData) bool) *NASDAQHistoricalAPIResponse {
+	filtered := *ndr
+	filtered.Data.TradesTable.Rows = nil
+
+	for _, r := range ndr.Data.TradesTable.Rows {
+		if keep(r) {
+			filtered.Data.TradesTable.Rows = append(filtered.Data.TradesTable.Rows, r)
+		}
+	}
+
+	return &filtered
+}
+
+// GetNASDAQHistoricialDataCached returns the cached response if there is
+// one, otherwise it's fetched and cached. With revalidate set a cached
+// response is only used after NASDAQ confirms it hasn't changed, with
+// refreshIncomplete set the same goes for cached responses that might be
+// missing the last trading days, see MaybeIncomplete.
+func GetNASDAQHistoricialDataCached(c Cache, ticker, fromDate, toDate string, revalidate, refreshIncomplete bool) *NASDAQHistoricalAPIResponse {
+	key := historicalCacheKey(ticker, fromDate, toDate)
+
+	cached, ok, err := c.Get(key)
+	if err != nil {
+		panic(err)
+	}
+	if ok && !revalidate && !(refreshIncomplete && cached.MaybeIncomplete(toDate)) {
+		return cached
+	}
+
+	if l, isLocker := c.(Locker); isLocker {
+		// Another process might be fetching it, use what it fetched
+		unlock, err := l.Lock(key)
+		if err != nil {
+			panic(err)
+		}
+		defer unlock()
+
+		// Read past the responses kept in memory, which are what was cached
+		// before the lock was taken
+		latest, found, err := sharedCache(c).Get(key)
 		if err != nil {
 			panic(err)
 		}
+		if found && (!ok || latest.FetchedAt.After(cached.FetchedAt)) {
+			if lc, isLRU := c.(*LRUCache); isLRU {
+				lc.add(key, latest)
+			}
+			return latest
+		}
+	}
 
-		err = os.WriteFile(file, j, 0777)
+	ndr, _ := CallNASDAQHistoricialAPIIfModified(ticker, fromDate, toDate, cached)
+
+	// Also written when not modified, to record when it was fetched
+	if len(ndr.Data.TradesTable.Rows) > 0 {
+		err = c.Set(key, ndr)
 		if err != nil {
 			panic(err)
 		}
@@ -280,14 +2044,61 @@ func GetNASDAQHistoricialDataCached(ticker, fromDate, toDate string) *NASDAQHist
 	return ndr
 }
 
+// historicalCacheKey is the CacheKey of a response on the selected market,
+// prefixed with the market unless it's the US one.
+func historicalCacheKey(ticker, fromDate, toDate string) string {
+	key := CacheKey(ticker, fromDate, toDate)
+	if m := market.Name(); m != "us" {
+		key = m + "-" + key
+	}
+	return key
+}
+
+// Cookie header sent with API requests if set, e.g. cookies copied from a
+// browser session on nasdaq.com to get past 403 Forbidden responses.
+var nasdaqCookie string
+
+var nasdaqHistoricalURL = "https://api.nasdaq.com/api/quote/{ticker}/historical?assetclass=stocks&fromdate={fromDate}&limit=9999&todate={toDate}&random=50"
+
 func CallNASDAQHistoricialAPI(ticker, fromDate, toDate string) (ndr *NASDAQHistoricalAPIResponse) {
-	url := "https://api.nasdaq.com/api/quote/{ticker}/historical?assetclass=stocks&fromdate={fromDate}&limit=9999&todate={toDate}&random=50"
+	ndr, _ = CallNASDAQHistoricialAPIIfModified(ticker, fromDate, toDate, nil)
+	return ndr
+}
+
+// fetchOlderRows fetches the rows before the oldest one of ndr when NASDAQ
+// returned fewer than the totalRecords it has, i.e. when the response was
+// capped by the request's limit, so that a long range doesn't silently start
+// later than asked for. The responses fetched are kept after those of ndr.
+// A warning is logged if rows are still missing.
+func fetchOlderRows(ticker, fromDate string, ndr *NASDAQHistoricalAPIResponse) *NASDAQHistoricalAPIResponse {
+	rows := ndr.Data.TradesTable.Rows
+	total := ndr.Data.TotalRecords
+	if int64(len(rows)) >= total || len(rows) == 0 {
+		return ndr
+	}
+
+	before := NASDAQDateToTime(rows[len(rows)-1].Date).AddDate(0, 0, -1).Format("2006-01-02")
+	if before >= fromDate {
+		older := CallNASDAQHistoricialAPI(ticker, fromDate, before)
+		if len(older.Data.TradesTable.Rows) > 0 {
+			pages := ndr.raw
+			ndr = MergeHistoricalData(older, ndr)
+			ndr.raw = append(pages[:len(pages):len(pages)], older.raw...)
+		}
+	}
+
+	if n := len(ndr.Data.TradesTable.Rows); int64(n) < total {
+		log.Printf("warning: NASDAQ returned %d of %d trading days for %s, the data starts on %s instead of %s",
+			n, total, ticker, NASDAQDateToTime(ndr.Data.TradesTable.Rows[n-1].Date).Format("2006-01-02"), fromDate)
+	}
 
-	url = strings.Replace(url, "{ticker}", strings.ToUpper(ticker), 1)
-	url = strings.Replace(url, "{fromDate}", fromDate, 1)
-	url = strings.Replace(url, "{toDate}", toDate, 1)
+	return ndr
+}
 
-	r, err := http.NewRequest(http.MethodGet, url, nil)
+// newNASDAQHistoricalRequest builds a request for the historical data of a
+// ticker on the selected market with the headers the NASDAQ API expects from a browser.
+func newNASDAQHistoricalRequest(ticker, fromDate, toDate string) *http.Request {
+	r, err := http.NewRequestWithContext(apiContext, http.MethodGet, market.HistoricalURL(ticker, fromDate, toDate), nil)
 	if err != nil {
 		panic(err)
 	}
@@ -298,21 +2109,55 @@ func CallNASDAQHistoricialAPI(ticker, fromDate, toDate string) (ndr *NASDAQHisto
 	r.Header.Add("origin", "https://www.nasdaq.com")
 	r.Header.Add("referer", "https://www.nasdaq.com/")
 	r.Header.Add("user-agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36")
+	if nasdaqCookie != "" {
+		r.Header.Add("cookie", nasdaqCookie)
+	}
+
+	return r
+}
 
-	c := http.Client{}
-	res, err := c.Do(r)
+// CallNASDAQHistoricialAPIIfModified sends a conditional request using the
+// ETag and Last-Modified validators of a previously fetched response. If
+// NASDAQ answers 304 Not Modified a copy of the cached response with its
+// FetchedAt updated is returned and modified is false.
+func CallNASDAQHistoricialAPIIfModified(ticker, fromDate, toDate string, cached *NASDAQHistoricalAPIResponse) (ndr *NASDAQHistoricalAPIResponse, modified bool) {
+	r := newNASDAQHistoricalRequest(ticker, fromDate, toDate)
+	url := r.URL.String()
+
+	if cached != nil {
+		if cached.ETag != "" {
+			r.Header.Add("if-none-match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			r.Header.Add("if-modified-since", cached.LastModified)
+		}
+	}
+
+	res, err := doWithRetries(&http.Client{}, r)
 	if err != nil {
-		panic(err)
+		panic(&APIError{URL: url, Err: err})
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotModified && cached != nil {
+		log.Printf("Not modified: %s", url)
+		// A copy, as the cached response may be shared
+		notModified := *cached
+		notModified.FetchedAt = time.Now()
+		return &notModified, false
+	}
+	if res.StatusCode != http.StatusOK {
+		panic(&APIError{URL: url, Err: fmt.Errorf("unexpected HTTP status %s", res.Status)})
 	}
 
 	gr, err := gzip.NewReader(res.Body)
 	if err != nil {
-		panic(err)
+		panic(&APIError{URL: url, Err: err})
 	}
 
 	data, err := io.ReadAll(gr)
 	if err != nil {
-		panic(err)
+		panic(&APIError{URL: url, Err: err})
 	}
 
 	max := len(data)
@@ -320,15 +2165,24 @@ func CallNASDAQHistoricialAPI(ticker, fromDate, toDate string) (ndr *NASDAQHisto
 		max = 1_000
 	}
 
-	fmt.Printf("Fetching URL: %s\n\n", url)
-	fmt.Println(string(data[0:max]))
-	fmt.Printf("\n\nRead %d chars\n", len(data))
+	// Logged rather than printed so that they don't end up in the results
+	log.Printf("Fetching URL: %s", url)
+	if verbose {
+		log.Printf("Response: %s", data[0:max])
+	}
+	log.Printf("Read %d chars", len(data))
 
-	ndr = new(NASDAQHistoricalAPIResponse)
-	err = json.Unmarshal(data, ndr)
+	ndr, err = market.ParseHistorical(data)
 	if err != nil {
-		panic(err)
+		panic(&APIError{URL: url, Err: fmt.Errorf("could not parse response: %w", err)})
 	}
+	ndr.raw = [][]byte{data}
 
-	return ndr
+	ndr = fetchOlderRows(ticker, fromDate, ndr)
+
+	ndr.ETag = res.Header.Get("etag")
+	ndr.LastModified = res.Header.Get("last-modified")
+	ndr.FetchedAt = time.Now()
+
+	return ndr, true
 }
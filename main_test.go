@@ -0,0 +1,1095 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+var update = flag.Bool("update", false, "Update the golden files in testdata/golden with the output")
+
+// TestMain runs the program itself instead of the tests when the golden
+// tests start the test binary with NASDAQ_RUN_MAIN set.
+func TestMain(m *testing.M) {
+	if os.Getenv("NASDAQ_RUN_MAIN") != "" {
+		main()
+		os.Exit(ExitOK)
+	}
+	os.Exit(m.Run())
+}
+
+// The golden tests run the program on the fixtures in testdata, as of a
+// fixed day, and compare what it prints to stdout to testdata/golden/<name>.
+var goldenTests = []struct {
+	name string
+	args []string
+}{
+	{"text", []string{"-s", "AAPL", "-f", "2020-01-01", "-t", "2020-12-31"}},
+	{"text-multi", []string{"-s", "AAPL,MSFT", "-f", "2020-01-01", "-t", "2020-12-31", "--frequency", "weekly", "-a", "100"}},
+	{"text-summary", []string{"-s", "AAPL,MSFT", "-f", "2020-03-01", "--summary-only"}},
+	{"json", []string{"-s", "AAPL", "-f", "2020-01-01", "-t", "2020-06-30", "--format", "json"}},
+	{"json-multi", []string{"-s", "AAPL,MSFT", "-f", "2020-01-01", "-t", "2020-06-30", "--format", "json", "--pretty=false"}},
+	{"csv-correlations", []string{"-s", "AAPL,MSFT", "-f", "2020-01-01", "-t", "2020-12-31", "--correlations"}},
+	{"csv-prices", []string{"prices", "-f", "2020-12-01", "-t", "2020-12-31", "MSFT"}},
+	{"csv-batch", []string{"batch", filepath.Join("testdata", "scenarios.csv")}},
+}
+
+func TestGolden(t *testing.T) {
+	for _, gt := range goldenTests {
+		t.Run(gt.name, func(t *testing.T) {
+			args := append(gt.args, "--data-dir", filepath.Join("testdata", "prices"))
+			if gt.args[0] != "prices" && gt.args[0] != "batch" {
+				args = append(args, "--today", "2021-01-04")
+			}
+
+			cmd := exec.Command(os.Args[0], args...)
+			cmd.Env = append(os.Environ(), "NASDAQ_RUN_MAIN=1")
+			var stderr bytes.Buffer
+			cmd.Stderr = &stderr
+			got, err := cmd.Output()
+			if err != nil {
+				t.Fatalf("%v: %v\n%s", args, err, stderr.String())
+			}
+
+			golden := filepath.Join("testdata", "golden", gt.name)
+			if *update {
+				err = os.WriteFile(golden, got, 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v, run the tests with -update to create it", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("output differs from %s, run the tests with -update if that's expected:\n%s", golden, got)
+			}
+		})
+	}
+}
+
+// runMain runs the program like the golden tests do, reading the fixtures in
+// testdata/prices, and returns what it printed and its exit code.
+func runMain(t *testing.T, args ...string) (stdout, stderr string, code int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], append(args, "--data-dir", filepath.Join("testdata", "prices"))...)
+	cmd.Env = append(os.Environ(), "NASDAQ_RUN_MAIN=1")
+	var out, errOut bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &errOut
+
+	err := cmd.Run()
+	if ee, ok := err.(*exec.ExitError); ok {
+		code = ee.ExitCode()
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	return out.String(), errOut.String(), code
+}
+
+// captureOutput returns what f writes to output.
+func captureOutput(t *testing.T, f func()) string {
+	t.Helper()
+	defer func(w io.Writer) { output = w }(output)
+	var buf bytes.Buffer
+	output = &buf
+	f()
+	return buf.String()
+}
+
+func TestAsOfValuesMidPeriod(t *testing.T) {
+	// Falling to 50 in the middle of the year, then back up to 150
+	source := testSource{"X": weekdayRows("2020-01-01", "2020-12-31", func(t time.Time) float64 {
+		if t.Month() <= 6 {
+			return 100 - 50*float64(t.YearDay())/183
+		}
+		return 50 + 100*float64(t.YearDay()-183)/183
+	})}
+
+	end := NewDCA("X", "2020-01-01", "2020-12-31", Monthly, 100, Options{Source: source})
+	mid := NewDCA("X", "2020-01-01", "2020-12-31", Monthly, 100, Options{Source: source, AsOf: ISODateToTime("2020-06-30")})
+
+	if mid.TotalInvested != 600 {
+		t.Errorf("invested %v as of the middle of the year, want 600", mid.TotalInvested)
+	}
+	if mid.TotalReturn >= mid.TotalInvested {
+		t.Errorf("return %v as of the bottom, want less than invested %v", mid.TotalReturn, mid.TotalInvested)
+	}
+	if end.TotalReturn <= end.TotalInvested {
+		t.Errorf("return %v at the end, want more than invested %v", end.TotalReturn, end.TotalInvested)
+	}
+	if got := mid.To.Format("2006-01-02"); got != "2020-06-30" {
+		t.Errorf("valued on %s, want 2020-06-30", got)
+	}
+}
+
+func TestAsOfBeforeTheFirstPurchase(t *testing.T) {
+	source := testSource{"X": weekdayRows("2020-01-01", "2020-12-31", flatPrice(100))}
+	opts := Options{Source: source, AsOf: ISODateToTime("2019-12-15")}
+
+	dp := NewDCAPortfolio([]SymbolSpec{{Symbol: "X"}}, "2019-12-01", "2020-12-31", Monthly, 100, opts)
+
+	if dp.TotalInvested != 0 {
+		t.Errorf("invested %v, want 0", dp.TotalInvested)
+	}
+	for name, v := range map[string]float64{"PNL": dp.PNL, "CAGR": dp.CAGR, "Lump-Sum PNL": dp.LumpSumPNL} {
+		if math.IsNaN(v) || v != 0 {
+			t.Errorf("%s is %v, want 0", name, v)
+		}
+	}
+	if dp.From.After(dp.To) {
+		t.Errorf("period %s - %s is inverted", dp.From.Format("2006-01-02"), dp.To.Format("2006-01-02"))
+	}
+}
+
+func TestDumpReportsMarshalErrors(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("no error for a value JSON can't represent")
+		}
+	}()
+	Dump(math.NaN())
+}
+
+func TestDumpCompactJSON(t *testing.T) {
+	defer func(pretty bool) { prettyJSON = pretty }(prettyJSON)
+	source := testSource{"X": weekdayRows("2020-01-01", "2020-03-31", flatPrice(10))}
+	dp := NewDCAPortfolio([]SymbolSpec{{Symbol: "X"}}, "2020-01-01", "2020-03-31", Monthly, 100, Options{Source: source})
+
+	prettyJSON = false
+	compact := captureOutput(t, func() { Dump(dp.Summary()) })
+	if n := strings.Count(compact, "\n"); n != 1 || !strings.HasSuffix(compact, "}\n") {
+		t.Errorf("compact JSON on %d lines, want one:\n%s", n, compact)
+	}
+
+	prettyJSON = true
+	pretty := captureOutput(t, func() { Dump(dp.Summary()) })
+	if strings.Count(pretty, "\n") < 3 {
+		t.Errorf("pretty JSON isn't indented:\n%s", pretty)
+	}
+	var a, b PortfolioSummary
+	if json.Unmarshal([]byte(compact), &a) != nil || json.Unmarshal([]byte(pretty), &b) != nil || a != b {
+		t.Errorf("compact %s and pretty %s JSON differ", compact, pretty)
+	}
+}
+
+func TestBestAndWorstPurchaseOnTradingDays(t *testing.T) {
+	// Cheapest in February and dearest in March, both months starting on a
+	// weekend
+	source := testSource{"X": weekdayRows("2020-01-01", "2020-06-30", func(t time.Time) float64 {
+		switch t.Month() {
+		case time.February:
+			return 50
+		case time.March:
+			return 200
+		}
+		return 100
+	})}
+
+	d := NewDCA("X", "2020-01-01", "2020-06-30", Monthly, 100, Options{Source: source})
+
+	if got := d.BestPriceDate.Format("2006-01-02"); d.BestPrice != 50 || got != "2020-02-03" {
+		t.Errorf("best purchase %v on %s, want 50 on 2020-02-03", d.BestPrice, got)
+	}
+	if got := d.WorstPriceDate.Format("2006-01-02"); d.WorstPrice != 200 || got != "2020-03-02" {
+		t.Errorf("worst purchase %v on %s, want 200 on 2020-03-02", d.WorstPrice, got)
+	}
+	for _, tx := range d.Transactions {
+		if wd := tx.Date.Weekday(); wd == time.Saturday || wd == time.Sunday {
+			t.Errorf("purchase recorded on %s, a %s", tx.Date.Format("2006-01-02"), wd)
+		}
+	}
+}
+
+func TestSummaryOnlyJSONLeavesOutPositions(t *testing.T) {
+	defer func(w io.Writer) { output = w }(output)
+	var buf bytes.Buffer
+	output = &buf
+
+	source := testSource{
+		"X": weekdayRows("2020-01-01", "2020-12-31", flatPrice(100)),
+		"Y": weekdayRows("2020-01-01", "2020-12-31", flatPrice(50)),
+	}
+	dp := NewDCAPortfolio([]SymbolSpec{{Symbol: "X"}, {Symbol: "Y"}}, "2020-01-01", "2020-12-31", Monthly, 100, Options{Source: source})
+	Dump(dp.Summary())
+
+	var summary map[string]interface{}
+	err := json.Unmarshal(buf.Bytes(), &summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := summary["Positions"]; ok {
+		t.Error("summary has the positions")
+	}
+	for _, field := range []string{"TotalInvested", "TotalReturn", "PNL", "CAGR", "From", "To"} {
+		if _, ok := summary[field]; !ok {
+			t.Errorf("summary has no %s", field)
+		}
+	}
+}
+
+func TestCAGROfContributionsOverTime(t *testing.T) {
+	// Growing 20% a year
+	start := ISODateToTime("2020-01-01")
+	source := testSource{"X": weekdayRows("2020-01-01", "2021-12-31", func(t time.Time) float64 {
+		return 100 * math.Pow(1.2, t.Sub(start).Hours()/24/365.25)
+	})}
+
+	d := NewDCA("X", "2020-01-01", "2021-12-31", Monthly, 100, Options{Source: source, AsOf: ISODateToTime("2021-12-31")})
+
+	if math.Abs(d.CAGR-20) > 0.5 {
+		t.Errorf("CAGR %.2f %%, want about 20 %% however late the money was invested", d.CAGR)
+	}
+}
+
+func TestTodayOnlyPinsTheDate(t *testing.T) {
+	defer func(now func() time.Time) { Now = now }(Now)
+	Now = func() time.Time { return ISODateToTime("2021-01-04") }
+
+	serveNASDAQ(t, func(w http.ResponseWriter, r *http.Request) {
+		writeNASDAQResponse(w, "X", weekdayRows("2020-12-01", "2020-12-31", flatPrice(10)))
+	})
+
+	ndr := CallNASDAQHistoricialAPI("X", "2020-12-01", "2020-12-31")
+
+	if Today() != "2021-01-04" {
+		t.Errorf("today is %s, want the pinned 2021-01-04", Today())
+	}
+	if since := time.Since(ndr.FetchedAt); since < 0 || since > time.Minute {
+		t.Errorf("fetched at %v, want the real time", ndr.FetchedAt)
+	}
+}
+
+func TestTodayIsTheDefaultEndDate(t *testing.T) {
+	out, stderr, code := runMain(t, "-s", "AAPL", "-f", "2020-12-01", "--today", "2020-12-15", "--format", "json", "--summary-only")
+	if code != ExitOK {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+
+	var summary struct{ To time.Time }
+	err := json.Unmarshal([]byte(out), &summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := summary.To.Format("2006-01-02"); got != "2020-12-15" {
+		t.Errorf("ends on %s, want the pinned today 2020-12-15", got)
+	}
+}
+
+func FuzzParseUSD(f *testing.F) {
+	for _, seed := range []string{"$1,234.56", "", "N/A", "12.5", "$-0.01", "1,23", "NaN", "$1e400"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, usd string) {
+		v, err := ParseUSD(usd)
+		if err != nil {
+			if v != 0 {
+				t.Errorf("ParseUSD(%q) = %v with error %v, want 0", usd, v, err)
+			}
+		} else if math.IsNaN(v) || math.IsInf(v, 0) {
+			t.Errorf("ParseUSD(%q) = %v, want a finite price or an error", usd, v)
+		}
+		if !strings.ContainsAny(usd, "0123456789") && err == nil {
+			t.Errorf("ParseUSD(%q) = %v without any digits, want an error", usd, v)
+		}
+
+		// Trading days never panic on bad prices either
+		td := &TradingData{Open: usd, High: usd, Low: usd, Close: usd}
+		if p := td.AvgPrice(); (err == nil && p != v) || (err != nil && p != 0) {
+			t.Errorf("price %v for %q, want %v", p, usd, v)
+		}
+		if td.HasPrice() != (err == nil) {
+			t.Errorf("HasPrice for %q is %v with error %v", usd, td.HasPrice(), err)
+		}
+	})
+}
+
+func FuzzNASDAQDate(f *testing.F) {
+	for _, seed := range []string{"$1,234.56", "", "N/A", "01/02/2006", "2024-01-15", "13/45/2020", " 02/29/2020 "} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, date string) {
+		d, err := ParseNASDAQDate(date)
+		if err != nil {
+			if !d.IsZero() {
+				t.Errorf("ParseNASDAQDate(%q) = %v with error %v, want the zero time", date, d, err)
+			}
+			return
+		}
+		if !strings.ContainsAny(date, "0123456789") {
+			t.Errorf("ParseNASDAQDate(%q) = %v without any digits, want an error", date, d)
+		}
+
+		// Normalized dates parse back to the same day
+		again, err := ParseNASDAQDate(d.Format(nasdaqDateLayout))
+		if err != nil || !again.Equal(d) {
+			t.Errorf("%q normalized to %s parses to %v, %v", date, d.Format(nasdaqDateLayout), again, err)
+		}
+	})
+}
+
+// zeroPriceOn returns a price function for weekdayRows that's p, except 0 on
+// the given ISO date.
+func zeroPriceOn(date string, p float64) func(time.Time) float64 {
+	return func(t time.Time) float64 {
+		if t.Format("2006-01-02") == date {
+			return 0
+		}
+		return p
+	}
+}
+
+func TestMinPriceSkipsZeroPriceRows(t *testing.T) {
+	source := testSource{"X": weekdayRows("2020-01-01", "2020-03-31", zeroPriceOn("2020-02-03", 10))}
+
+	d := NewDCA("X", "2020-01-01", "2020-03-31", Monthly, 100, Options{Source: source, MinPrice: 0.01})
+
+	for _, r := range d.prices.Data.TradesTable.Rows {
+		if r.Date == "02/03/2020" {
+			t.Error("the zero price row on 02/03/2020 wasn't skipped")
+		}
+	}
+	if len(d.Transactions) != 3 {
+		t.Fatalf("%d purchases, want 3", len(d.Transactions))
+	}
+	if tx := d.Transactions[1]; tx.Date.Format("2006-01-02") != "2020-02-04" || tx.Price != 10 {
+		t.Errorf("February purchase at %v on %s, want 10 on the next trading day 2020-02-04", tx.Price, tx.Date.Format("2006-01-02"))
+	}
+}
+
+func TestZeroPriceKeepsUnitsFinite(t *testing.T) {
+	source := testSource{"X": weekdayRows("2020-01-01", "2020-03-31", zeroPriceOn("2020-02-03", 10))}
+
+	for _, carry := range []bool{false, true} {
+		d := NewDCA("X", "2020-01-01", "2020-03-31", Monthly, 100, Options{Source: source, CarrySkipped: carry})
+
+		if math.IsInf(d.Units, 0) || math.IsNaN(d.Units) {
+			t.Fatalf("units %v with carry %v", d.Units, carry)
+		}
+		want := 200.0
+		if carry {
+			want = 300
+		}
+		if d.TotalInvested != want || !approx(d.Units, want/10) {
+			t.Errorf("invested %v in %v units with carry %v, want %v in %v", d.TotalInvested, d.Units, carry, want, want/10)
+		}
+	}
+}
+
+func TestInterpolatedPriceOverAGap(t *testing.T) {
+	ndr := &NASDAQHistoricalAPIResponse{}
+	ndr.Data.Symbol = "X"
+	ndr.Data.TradesTable.Rows = append(
+		weekdayRows("2020-01-13", "2020-01-17", flatPrice(20)),
+		weekdayRows("2020-01-06", "2020-01-10", flatPrice(10))...,
+	)
+	// Leave a gap of six days between 01/08 and 01/14
+	ndr.Data.TradesTable.Rows = append(ndr.Data.TradesTable.Rows[:4], ndr.Data.TradesTable.Rows[7:]...)
+
+	for date, want := range map[string]float64{
+		"2020-01-08": 10,
+		"2020-01-09": 10 + 10.0/6,
+		"2020-01-11": 15,
+		"2020-01-14": 20,
+	} {
+		got, err := ndr.InterpolatedPrice(ISODateToTime(date), 7*24*time.Hour)
+		if err != nil || math.Abs(got-want) > 1e-9 {
+			t.Errorf("price on %s is %v, %v, want %v", date, got, err, want)
+		}
+	}
+
+	if _, err := ndr.InterpolatedPrice(ISODateToTime("2020-01-11"), 2*24*time.Hour); err == nil {
+		t.Error("no error interpolating over a gap longer than the window")
+	}
+}
+
+// withPrinting sets the precision, printer and currency symbol for the
+// duration of the test.
+func withPrinting(t *testing.T, decimals int, tag language.Tag, symbol string) {
+	t.Helper()
+	savedPrecision, savedPrinter, savedSymbol := precision, printer, currencySymbol
+	precision, printer, currencySymbol = decimals, message.NewPrinter(tag), symbol
+	t.Cleanup(func() { precision, printer, currencySymbol = savedPrecision, savedPrinter, savedSymbol })
+}
+
+func TestMoneyPrecision(t *testing.T) {
+	for decimals, want := range map[int]string{0: "$1,235", 2: "$1,234.57"} {
+		withPrinting(t, decimals, language.English, "$")
+		if got := Money(1234.567); got != want {
+			t.Errorf("Money(1234.567) at precision %d is %s, want %s", decimals, got, want)
+		}
+	}
+
+	withPrinting(t, 0, language.English, "$")
+	source := testSource{"X": weekdayRows("2020-01-01", "2020-03-31", flatPrice(3))}
+	d := NewDCA("X", "2020-01-01", "2020-03-31", Monthly, 100.4, Options{Source: source})
+	if got := captureOutput(t, d.Print); !strings.Contains(got, "Total Invested : $301\n") {
+		t.Errorf("no total invested at precision 0 in:\n%s", got)
+	}
+}
+
+func TestMoneyGroupsThousands(t *testing.T) {
+	withPrinting(t, 2, language.English, "$")
+
+	for v, want := range map[float64]string{
+		1234567.891: "$1,234,567.89",
+		-98765.4:    "-$98,765.40",
+		999:         "$999.00",
+	} {
+		if got := Money(v); got != want {
+			t.Errorf("Money(%v) = %s, want %s", v, got, want)
+		}
+	}
+}
+
+func TestGermanLocale(t *testing.T) {
+	withPrinting(t, 2, language.German, "€")
+
+	if got := Money(1234567.891); got != "€1.234.567,89" {
+		t.Errorf("Money(1234567.891) = %s, want €1.234.567,89", got)
+	}
+
+	source := testSource{"X": weekdayRows("2020-01-01", "2020-03-31", flatPrice(4))}
+	d := NewDCA("X", "2020-01-01", "2020-03-31", Monthly, 1000, Options{Source: source})
+	got := captureOutput(t, d.Print)
+	for _, want := range []string{"Units          : 750,0000\n", "Total Invested : €3.000,00\n", "PNL            : 0,00 %\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("no %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestExecutionLagBuysOnTheNextTradingDay(t *testing.T) {
+	// The price is the day of the month
+	source := testSource{"X": weekdayRows("2020-01-01", "2020-03-31", func(t time.Time) float64 { return float64(t.Day()) })}
+
+	d := NewDCA("X", "2020-01-01", "2020-03-31", Monthly, 100, Options{Source: source, ExecutionLag: 1})
+
+	// Scheduled on Wednesday 01/01, Saturday 02/01 and Sunday 03/01
+	want := []string{"2020-01-02", "2020-02-04", "2020-03-03"}
+	if len(d.Transactions) != len(want) {
+		t.Fatalf("%d purchases, want %d", len(d.Transactions), len(want))
+	}
+	for i, tx := range d.Transactions {
+		if got := tx.Date.Format("2006-01-02"); got != want[i] || tx.Price != float64(tx.Date.Day()) {
+			t.Errorf("purchase %d at %v on %s, want the price on %s", i+1, tx.Price, got, want[i])
+		}
+	}
+}
+
+func TestRoundToCents(t *testing.T) {
+	source := testSource{"X": weekdayRows("2020-01-01", "2020-12-31", flatPrice(7))}
+
+	d := NewDCA("X", "2020-01-01", "2020-12-31", Monthly, 100.0/3, Options{Source: source, RoundToCents: true})
+
+	for _, tx := range d.Transactions {
+		if cents := tx.Amount * 100; math.Abs(cents-math.Round(cents)) > 1e-6 {
+			t.Errorf("invested %v on %s, not a whole number of cents", tx.Amount, tx.Date.Format("2006-01-02"))
+		}
+	}
+	// The fractions left over are invested later
+	if math.Abs(d.TotalInvested+d.RoundingResidual-400) > 1e-6 || d.RoundingResidual >= 0.01 {
+		t.Errorf("invested %v with %v left over, want 400 in total", d.TotalInvested, d.RoundingResidual)
+	}
+}
+
+func TestUnitsPerPositionArePrinted(t *testing.T) {
+	source := testSource{
+		"X": weekdayRows("2020-01-01", "2020-03-31", flatPrice(8)),
+		"Y": weekdayRows("2020-01-01", "2020-03-31", flatPrice(16)),
+	}
+	dp := NewDCAPortfolio([]SymbolSpec{{Symbol: "X"}, {Symbol: "Y"}}, "2020-01-01", "2020-03-31", Monthly, 200, Options{Source: source})
+
+	got := captureOutput(t, dp.Print)
+	for _, want := range []string{"Symbol         : X\nPeriod         : 2020-01-01 - 2020-03-31\nUnits          : 37.5000\n", "Symbol         : Y\nPeriod         : 2020-01-01 - 2020-03-31\nUnits          : 18.7500\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("no %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestHoldingsBreakdownSumsToTotalReturn(t *testing.T) {
+	source := testSource{
+		"X": weekdayRows("2020-01-01", "2020-06-30", func(t time.Time) float64 { return 10 + float64(t.YearDay())/10 }),
+		"Y": weekdayRows("2020-01-01", "2020-06-30", func(t time.Time) float64 { return 50 - float64(t.YearDay())/10 }),
+	}
+	// X twice in two accounts, held as one symbol
+	specs := []SymbolSpec{{Symbol: "X"}, {Symbol: "Y"}, {Symbol: "X", Account: "ira"}}
+	dp := NewDCAPortfolio(specs, "2020-01-01", "2020-06-30", Monthly, 300, Options{Source: source})
+
+	out := captureOutput(t, func() {
+		Dump(struct {
+			*DCAPortfolio
+			Holdings map[string]*Holding
+		}{dp, dp.Holdings()})
+	})
+	var parsed struct {
+		TotalReturn float64
+		Holdings    map[string]Holding
+	}
+	err := json.Unmarshal([]byte(out), &parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(parsed.Holdings) != 2 {
+		t.Errorf("holdings %v, want X and Y", parsed.Holdings)
+	}
+	var sum float64
+	for symbol, h := range parsed.Holdings {
+		sum += h.Value
+		if math.Abs(h.Units*h.Price-h.Value) > 1e-6 {
+			t.Errorf("%s holding %v units at %v worth %v", symbol, h.Units, h.Price, h.Value)
+		}
+	}
+	if math.Abs(sum-parsed.TotalReturn) > 1e-6 {
+		t.Errorf("holdings worth %v, want the total return %v", sum, parsed.TotalReturn)
+	}
+}
+
+func TestProrateTheFinalPartialMonth(t *testing.T) {
+	source := testSource{"X": weekdayRows("2020-01-01", "2020-03-31", flatPrice(100))}
+
+	full := NewDCA("X", "2020-01-01", "2020-03-16", Monthly, 100, Options{Source: source})
+	prorated := NewDCA("X", "2020-01-01", "2020-03-16", Monthly, 100, Options{Source: source, Prorate: true})
+
+	if full.TotalInvested != 300 {
+		t.Errorf("invested %v without prorating, want 300", full.TotalInvested)
+	}
+	// A final buy on March 16 for the 15 of the 31 days to April 1 covered
+	if want := 300 + 100*15.0/31; !approx(prorated.TotalInvested, want) {
+		t.Errorf("invested %v prorated, want %v", prorated.TotalInvested, want)
+	}
+	last := prorated.Transactions[len(prorated.Transactions)-1]
+	if got := last.Date.Format("2006-01-02"); got != "2020-03-16" || !approx(last.Amount, 100*15.0/31) {
+		t.Errorf("final buy of %v on %s, want %v on 2020-03-16", last.Amount, got, 100*15.0/31)
+	}
+}
+
+func TestPositionsWithTheirOwnSchedules(t *testing.T) {
+	source := testSource{
+		"X": weekdayRows("2020-01-01", "2020-03-31", flatPrice(10)),
+		"Y": weekdayRows("2020-01-01", "2020-03-31", flatPrice(20)),
+	}
+	specs, err := ParseSymbolSpecs([]string{"X:frequency=weekly:amount=50", "Y"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dp := NewDCAPortfolio(specs, "2020-01-01", "2020-03-31", Monthly, 200, Options{Source: source})
+
+	// Every Wednesday from January 1 to March 25, and on the first of the
+	// month
+	for i, want := range []int{13, 3} {
+		if got := len(dp.Positions[i].Transactions); got != want {
+			t.Errorf("%s bought %d times, want %d", dp.Positions[i].Symbol, got, want)
+		}
+	}
+	if want := 13*50.0 + 3*200.0; dp.TotalInvested != want {
+		t.Errorf("invested %v in total, want %v", dp.TotalInvested, want)
+	}
+	if sum := dp.Positions[0].TotalInvested + dp.Positions[1].TotalInvested; dp.TotalInvested != sum {
+		t.Errorf("invested %v in total, want the positions' %v", dp.TotalInvested, sum)
+	}
+}
+
+func TestDuplicateSymbolsAreInvestedOnce(t *testing.T) {
+	source := testSource{"X": weekdayRows("2020-01-01", "2020-03-31", flatPrice(10))}
+	specs := []SymbolSpec{{Symbol: "X"}, {Symbol: "X"}}
+
+	dp := NewDCAPortfolio(specs, "2020-01-01", "2020-03-31", Monthly, 100, Options{Source: source})
+
+	if got := strings.Count(captureOutput(t, dp.Print), "Symbol         : X\n"); got != 1 {
+		t.Errorf("X printed %d times, want once", got)
+	}
+	if dp.TotalInvested != 300 {
+		t.Errorf("invested %v, want all 300 in X once", dp.TotalInvested)
+	}
+
+	doubled := NewDCAPortfolio(specs, "2020-01-01", "2020-03-31", Monthly, 100, Options{Source: source, AllowDuplicates: true})
+	if len(doubled.Positions) != 2 {
+		t.Errorf("%d positions allowing duplicates, want 2", len(doubled.Positions))
+	}
+}
+
+func TestGrowthIsTheReturnOnContributions(t *testing.T) {
+	source := testSource{"X": weekdayRows("2020-01-01", "2020-12-31", func(t time.Time) float64 { return 10 + float64(t.YearDay())/10 })}
+
+	dp := NewDCAPortfolio([]SymbolSpec{{Symbol: "X"}}, "2020-01-01", "2020-12-31", Monthly, 100, Options{Source: source})
+
+	if dp.Contributions != dp.TotalInvested {
+		t.Errorf("contributions %v, want the %v invested", dp.Contributions, dp.TotalInvested)
+	}
+	if want := dp.TotalReturn - dp.TotalInvested; dp.Growth != want || want <= 0 {
+		t.Errorf("growth %v, want %v", dp.Growth, want)
+	}
+	got := captureOutput(t, dp.PrintSummary)
+	for _, want := range []string{"Contributions  : " + Money(dp.Contributions) + "\n", "Growth         : " + Money(dp.Growth) + "\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("no %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestParseHistoricalWithADateLayout(t *testing.T) {
+	response := func(date string) []byte {
+		return []byte(`{"data": {"symbol": "X", "tradesTable": {"rows": [{"date": "` + date + `", "close": "$10.00"}]}}}`)
+	}
+
+	for _, tc := range []struct{ layout, date string }{
+		{"2006-01-02", "2020-01-03"},
+		{"02.01.2006", "03.01.2020"},
+		{"", "01/03/2020"},
+	} {
+		ndr, err := USMarket{DateLayout: tc.layout}.ParseHistorical(response(tc.date))
+		if err != nil {
+			t.Errorf("%s with layout %q: %v", tc.date, tc.layout, err)
+			continue
+		}
+		if got := ndr.Data.TradesTable.Rows[0].Date; got != "01/03/2020" {
+			t.Errorf("%s with layout %q normalized to %s, want 01/03/2020", tc.date, tc.layout, got)
+		}
+	}
+
+	_, err := USMarket{}.ParseHistorical(response("3 Jan 2020"))
+	if err == nil || err.Error() != "unrecognized trading date format '3 Jan 2020'" {
+		t.Errorf("error %v for an unknown date format", err)
+	}
+}
+
+func TestIncomeContributions(t *testing.T) {
+	source := testSource{"X": weekdayRows("2020-01-01", "2021-12-31", flatPrice(10))}
+
+	amount := IncomeContribution(5000, 10, Monthly.PerYear())
+	if amount != 500 {
+		t.Fatalf("contributing %v a month, want 10 %% of 5000", amount)
+	}
+	if weekly := IncomeContribution(5000, 10, Weekly.PerYear()); !approx(weekly*52, 6000) {
+		t.Errorf("contributing %v a week, want 10 %% of the 60000 a year", weekly)
+	}
+
+	// The income grows 3% a year
+	d := NewDCA("X", "2020-01-01", "2021-12-31", Monthly, amount, Options{Source: source, AmountGrowth: 0.03})
+	for _, tx := range d.Transactions {
+		want := 500.0
+		if tx.Date.Year() == 2021 {
+			want = 515
+		}
+		if !approx(tx.Amount, want) {
+			t.Errorf("contributed %v on %s, want %v", tx.Amount, tx.Date.Format("2006-01-02"), want)
+		}
+	}
+	if len(d.Transactions) != 24 {
+		t.Errorf("%d contributions, want one a month", len(d.Transactions))
+	}
+}
+
+func TestCookieIsSentWithRequests(t *testing.T) {
+	defer func(cookie string) { nasdaqCookie = cookie }(nasdaqCookie)
+	nasdaqCookie = "ak_bmsc=abc; bm_sv=def"
+
+	var got []string
+	serveNASDAQ(t, func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Values("cookie")
+		writeNASDAQResponse(w, "X", weekdayRows("2020-01-01", "2020-01-31", flatPrice(10)))
+	})
+
+	CallNASDAQHistoricialAPI("X", "2020-01-01", "2020-01-31")
+
+	if len(got) != 1 || got[0] != nasdaqCookie {
+		t.Errorf("cookie headers %q, want %q", got, nasdaqCookie)
+	}
+}
+
+func TestOutputToAFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "results", "aapl.json")
+	stdout, stderr, code := runMain(t, "-s", "AAPL", "-f", "2020-01-01", "-t", "2020-06-30", "--today", "2021-01-04", "--format", "json", "--output", file)
+	if code != ExitOK {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if len(stdout) != 0 {
+		t.Errorf("printed to stdout with --output:\n%s", stdout)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dp DCAPortfolio
+	err = json.Unmarshal(data, &dp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dp.Positions) != 1 || dp.Positions[0].Symbol != "AAPL" || dp.TotalInvested != 3000 {
+		t.Errorf("read back %d positions investing %v, want 3000 in AAPL", len(dp.Positions), dp.TotalInvested)
+	}
+}
+
+func TestMergePortfolios(t *testing.T) {
+	source := testSource{
+		"X": weekdayRows("2020-01-01", "2020-12-31", func(t time.Time) float64 { return 10 + float64(t.YearDay())/10 }),
+		"Y": weekdayRows("2020-01-01", "2020-12-31", flatPrice(20)),
+	}
+	taxable := NewDCAPortfolio([]SymbolSpec{{Symbol: "X", Account: "taxable"}}, "2020-01-01", "2020-12-31", Monthly, 100, Options{Source: source})
+	ira := NewDCAPortfolio([]SymbolSpec{{Symbol: "X", Account: "ira"}, {Symbol: "Y", Account: "ira"}}, "2020-01-01", "2020-12-31", Monthly, 200, Options{Source: source})
+
+	merged := Merge(taxable, ira)
+
+	if len(merged.Positions) != 3 {
+		t.Errorf("%d positions, want X in both accounts kept apart and Y", len(merged.Positions))
+	}
+	if want := taxable.TotalInvested + ira.TotalInvested; merged.TotalInvested != want || want != 3600 {
+		t.Errorf("invested %v, want %v", merged.TotalInvested, want)
+	}
+	if want := taxable.TotalReturn + ira.TotalReturn; !approx(merged.TotalReturn, want) {
+		t.Errorf("return %v, want %v", merged.TotalReturn, want)
+	}
+	if want := PNL(merged.TotalInvested, merged.TotalReturn, merged.ValueSeries()); !approx(merged.PNL, want) || merged.PNL <= ira.PNL || merged.PNL >= taxable.PNL {
+		t.Errorf("PNL %v, want %v between the accounts' %v and %v", merged.PNL, want, ira.PNL, taxable.PNL)
+	}
+	if got := merged.Accounts(); len(got) != 2 || got[0] != "taxable" || got[1] != "ira" {
+		t.Errorf("accounts %v, want taxable and ira", got)
+	}
+}
+
+func TestAccountLabelsInTheOutput(t *testing.T) {
+	source := testSource{"X": weekdayRows("2020-01-01", "2020-03-31", flatPrice(10))}
+	specs, err := ParseSymbolSpecs([]string{"X:account=ira", "X:account=taxable", "X"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dp := NewDCAPortfolio(specs, "2020-01-01", "2020-03-31", Monthly, 300, Options{Source: source})
+
+	got := captureOutput(t, dp.Print)
+	for _, want := range []string{"Account        : ira\nTotal Invested : $300.00\n", "Account        : taxable\n", "Account        : (none)\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("no %q in:\n%s", want, got)
+		}
+	}
+
+	var parsed struct{ Positions []struct{ Account string } }
+	err = json.Unmarshal([]byte(captureOutput(t, func() { Dump(dp) })), &parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []string{"ira", "taxable", ""} {
+		if got := parsed.Positions[i].Account; got != want {
+			t.Errorf("position %d in account %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestCurrencySymbol(t *testing.T) {
+	out, stderr, code := runMain(t, "-s", "AAPL", "-f", "2020-01-01", "-t", "2020-03-31", "--today", "2021-01-04", "--currency-symbol", "€")
+	if code != ExitOK {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+
+	if !strings.Contains(out, "Total Invested : €1,500.00\n") {
+		t.Errorf("no total invested in euros in:\n%s", out)
+	}
+	if strings.Contains(out, "$") {
+		t.Errorf("dollars printed with the € symbol:\n%s", out)
+	}
+}
+
+func TestValidateDateRangeErrors(t *testing.T) {
+	for _, tc := range []struct{ from, to, want string }{
+		{"2025-01-01", "2020-01-01", "start date 2025-01-01 is after end date 2020-01-01"},
+		{"2020-02-30", "2020-12-31", "invalid start date '2020-02-30', expected YYYY-MM-DD"},
+		{"2020-01-01", "12/31/2020", "invalid end date '12/31/2020', expected YYYY-MM-DD"},
+	} {
+		err := ValidateDateRange(tc.from, tc.to)
+		if err == nil || err.Error() != tc.want {
+			t.Errorf("%s - %s: error %v, want %q", tc.from, tc.to, err, tc.want)
+		}
+	}
+	if err := ValidateDateRange("2020-01-01", "2020-01-01"); err != nil {
+		t.Errorf("error %v for a single day", err)
+	}
+
+	defer func() {
+		err, _ := recover().(error)
+		if err == nil || err.Error() != "start date 2025-01-01 is after end date 2020-01-01" {
+			t.Errorf("DCA panicked with %v, want the date order error", err)
+		}
+	}()
+	NewDCA("X", "2025-01-01", "2020-01-01", Monthly, 100, Options{Source: testSource{}})
+}
+
+func TestStrictDatesFailOnAGap(t *testing.T) {
+	// No trading in February, rows newest first
+	rows := append(weekdayRows("2020-03-01", "2020-03-31", flatPrice(10)), weekdayRows("2020-01-01", "2020-01-31", flatPrice(10))...)
+	source := testSource{"X": rows}
+
+	if d := NewDCA("X", "2020-01-01", "2020-03-31", Monthly, 100, Options{Source: source}); len(d.Transactions) != 3 {
+		t.Errorf("%d purchases without --strict-dates, want 3", len(d.Transactions))
+	}
+	// Weekends are within the tolerance
+	NewDCA("X", "2020-03-01", "2020-03-31", Weekly, 100, Options{Source: source, StrictDates: true, StrictDatesTolerance: 4 * 24 * time.Hour})
+
+	defer func() {
+		r := recover()
+		if r == nil || fmt.Sprint(r) != "no X trading day within 4 days of the purchase on 2020-02-01" {
+			t.Errorf("panicked with %v, want the February purchase to fail", r)
+		}
+	}()
+	NewDCA("X", "2020-01-01", "2020-03-31", Monthly, 100, Options{Source: source, StrictDates: true, StrictDatesTolerance: 4 * 24 * time.Hour})
+}
+
+func TestTransactionsInJSONOnlyWhenIncluded(t *testing.T) {
+	defer func(include bool) { includeTransactions = include }(includeTransactions)
+	source := testSource{"X": weekdayRows("2020-01-01", "2020-03-31", flatPrice(10))}
+	dp := NewDCAPortfolio([]SymbolSpec{{Symbol: "X"}}, "2020-01-01", "2020-03-31", Monthly, 100, Options{Source: source})
+
+	positions := func() []map[string]json.RawMessage {
+		var parsed struct{ Positions []map[string]json.RawMessage }
+		err := json.Unmarshal([]byte(captureOutput(t, func() { Dump(dp) })), &parsed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed.Positions
+	}
+
+	includeTransactions = false
+	if _, ok := positions()[0]["Transactions"]; ok {
+		t.Error("transactions in the JSON by default")
+	}
+
+	includeTransactions = true
+	var transactions []struct {
+		Date   time.Time
+		Price  float64
+		Amount float64
+		Units  float64
+	}
+	err := json.Unmarshal(positions()[0]["Transactions"], &transactions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transactions) != 3 || transactions[1].Date.Format("2006-01-02") != "2020-02-03" || transactions[1].Price != 10 || transactions[1].Amount != 100 || transactions[1].Units != 10 {
+		t.Errorf("transactions %+v, want the 3 purchases", transactions)
+	}
+}
+
+func TestMaxSymbols(t *testing.T) {
+	args := []string{"-s", "AAPL,MSFT", "-f", "2020-01-01", "-t", "2020-03-31", "--today", "2021-01-04"}
+
+	_, stderr, code := runMain(t, append(args, "--max-symbols", "1")...)
+	if code != ExitUsage || !strings.Contains(stderr, "2 symbols to run, more than the limit of 1, raise it with --max-symbols 2 to run them anyway") {
+		t.Errorf("exit code %d with more symbols than the limit:\n%s", code, stderr)
+	}
+
+	out, stderr, code := runMain(t, append(args, "--max-symbols", "2")...)
+	if code != ExitOK || !strings.Contains(out, "Symbol         : MSFT\n") {
+		t.Errorf("exit code %d with the limit raised:\n%s%s", code, out, stderr)
+	}
+}
+
+func TestDrawdownAlert(t *testing.T) {
+	defer func(alert float64) { drawdownAlert = alert }(drawdownAlert)
+	// Up to 200 by July, ending the year back at 120
+	source := testSource{"X": weekdayRows("2020-01-01", "2020-12-31", func(t time.Time) float64 {
+		if t.Month() <= 6 {
+			return 100 + 100*float64(t.YearDay())/183
+		}
+		return 200 - 80*float64(t.YearDay()-183)/183
+	})}
+	dp := NewDCAPortfolio([]SymbolSpec{{Symbol: "X"}}, "2020-01-01", "2020-12-31", Monthly, 100, Options{Source: source})
+
+	if dp.CurrentDrawdown < 20 {
+		t.Fatalf("current drawdown %.2f %%, want it down from the July high", dp.CurrentDrawdown)
+	}
+
+	drawdownAlert = 10
+	want := printer.Sprintf("WARNING        : down %.02f %% from its high, more than the 10.00 %% alert\n", dp.CurrentDrawdown)
+	if got := captureOutput(t, dp.PrintSummary); !strings.Contains(got, want) {
+		t.Errorf("no %q in:\n%s", want, got)
+	}
+
+	drawdownAlert = 50
+	if got := captureOutput(t, dp.PrintSummary); strings.Contains(got, "WARNING") {
+		t.Errorf("alert above the drawdown in:\n%s", got)
+	}
+}
+
+func TestQuarterlyAndYearlyPurchases(t *testing.T) {
+	source := testSource{"X": weekdayRows("2020-01-01", "2022-12-31", flatPrice(10))}
+
+	for _, tc := range []struct {
+		f       Frequency
+		perYear int
+	}{{Quarterly, 4}, {Yearly, 1}} {
+		d := NewDCA("X", "2020-01-01", "2022-12-31", tc.f, 100, Options{Source: source})
+		byYear := make(map[int]int)
+		for _, tx := range d.Transactions {
+			byYear[tx.Date.Year()]++
+		}
+		for year := 2020; year <= 2022; year++ {
+			if byYear[year] != tc.perYear {
+				t.Errorf("%d %s purchases in %d, want %d", byYear[year], tc.f, year, tc.perYear)
+			}
+		}
+	}
+
+	// Days past the end of a shorter month fall on its last day
+	for _, tc := range []struct {
+		from        string
+		months, day int
+		want        string
+	}{
+		{"2020-01-31", 3, 31, "2020-04-30"},
+		{"2020-04-30", 3, 31, "2020-07-31"},
+		{"2020-02-29", 12, 29, "2021-02-28"},
+		{"2021-02-28", 12, 29, "2022-02-28"},
+		{"2020-11-30", 3, 30, "2021-02-28"},
+	} {
+		if got := addMonths(ISODateToTime(tc.from), tc.months, tc.day).Format("2006-01-02"); got != tc.want {
+			t.Errorf("%d months after %s on day %d is %s, want %s", tc.months, tc.from, tc.day, got, tc.want)
+		}
+	}
+
+	for _, s := range []string{"quarterly", "Yearly "} {
+		if _, err := ParseFrequency(s); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestBiweeklyPurchases(t *testing.T) {
+	source := testSource{"X": weekdayRows("2020-01-01", "2020-12-31", flatPrice(10))}
+
+	d := NewDCA("X", "2020-01-01", "2020-12-31", Biweekly, 100, Options{Source: source})
+	if n := len(d.Transactions); n < 26 || n > 27 {
+		t.Errorf("%d biweekly purchases in a year, want about 26", n)
+	}
+	for i := 1; i < len(d.Transactions); i++ {
+		if days := d.Transactions[i].Date.Sub(d.Transactions[i-1].Date).Hours() / 24; days != 14 {
+			t.Errorf("%v days between purchases on %s, want 14", days, d.Transactions[i].Date.Format("2006-01-02"))
+		}
+	}
+
+	// Starting on the first Friday
+	friday := time.Friday
+	d = NewDCA("X", "2020-01-01", "2020-12-31", Biweekly, 100, Options{Source: source, BiweeklyAnchor: &friday})
+	if got := d.Transactions[0].Date.Format("2006-01-02"); got != "2020-01-03" {
+		t.Errorf("first purchase on %s, want Friday 2020-01-03", got)
+	}
+	for _, tx := range d.Transactions {
+		if tx.Date.Weekday() != time.Friday {
+			t.Errorf("purchase on %s, a %s", tx.Date.Format("2006-01-02"), tx.Date.Weekday())
+		}
+	}
+}
+
+func TestFetchOlderRowsBeyondTheLimit(t *testing.T) {
+	all := weekdayRows("2020-01-01", "2020-03-31", flatPrice(10))
+	// Answers with the newest 20 rows in the range asked for and the total
+	// in it, or with no older rows at all unless older is set
+	limited := func(older bool) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			from, to := ISODateToTime(r.URL.Query().Get("fromdate")), ISODateToTime(r.URL.Query().Get("todate"))
+			rows := []*TradingData{}
+			for _, row := range all {
+				d := NASDAQDateToTime(row.Date)
+				if !d.Before(from) && !d.After(to) {
+					rows = append(rows, row)
+				}
+			}
+			if !older && r.URL.Query().Get("todate") != "2020-03-31" {
+				rows = rows[:0]
+			}
+			ndr := &NASDAQHistoricalAPIResponse{}
+			ndr.Data.Symbol = "X"
+			ndr.Data.TotalRecords = int64(len(rows))
+			if !older {
+				ndr.Data.TotalRecords = int64(len(all))
+			}
+			if len(rows) > 20 {
+				rows = rows[:20]
+			}
+			ndr.Data.TradesTable.Rows = rows
+			w.Header().Set("content-encoding", "gzip")
+			gw := gzip.NewWriter(w)
+			json.NewEncoder(gw).Encode(ndr)
+			gw.Close()
+		}
+	}
+
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	serveNASDAQ(t, limited(true))
+	ndr := CallNASDAQHistoricialAPI("X", "2020-01-01", "2020-03-31")
+	rows := ndr.Data.TradesTable.Rows
+	if len(rows) != len(all) || rows[0].Date != all[0].Date || rows[len(rows)-1].Date != "01/01/2020" {
+		t.Errorf("%d rows from %s to %s, want all %d", len(rows), rows[len(rows)-1].Date, rows[0].Date, len(all))
+	}
+	if strings.Contains(logged.String(), "warning") {
+		t.Errorf("warned with all the rows fetched:\n%s", logged.String())
+	}
+
+	// The older rows can't be fetched
+	serveNASDAQ(t, limited(false))
+	CallNASDAQHistoricialAPI("X", "2020-01-01", "2020-03-31")
+	if !strings.Contains(logged.String(), fmt.Sprintf("warning: NASDAQ returned 20 of %d trading days for X, the data starts on 2020-03-04 instead of 2020-01-01", len(all))) {
+		t.Errorf("no warning about the missing rows in:\n%s", logged.String())
+	}
+}
+
+func TestEffectiveDatesWithALateInception(t *testing.T) {
+	// Listed on a Saturday, buying from the Monday after, on the 9th
+	source := testSource{"X": weekdayRows("2020-03-07", "2020-06-30", flatPrice(10))}
+	d := NewDCA("X", "2020-01-01", "2020-06-30", Monthly, 100, Options{Source: source})
+
+	first, last, ok := d.EffectivePeriod()
+	if !ok || first.Format("2006-01-02") != "2020-03-09" || last.Format("2006-01-02") != "2020-06-09" {
+		t.Errorf("effective period %s - %s, want the trading days 2020-03-09 - 2020-06-09", first.Format("2006-01-02"), last.Format("2006-01-02"))
+	}
+
+	defer func(show bool) { showEffectiveDates = show }(showEffectiveDates)
+	showEffectiveDates = true
+	got := captureOutput(t, d.Print)
+	want := "Requested      : 2020-01-01 - 2020-06-30\nEffective      : 2020-03-09 - 2020-06-09\n"
+	if !strings.Contains(got, want) {
+		t.Errorf("no %q in:\n%s", want, got)
+	}
+
+	showEffectiveDates = false
+	if got := captureOutput(t, d.Print); strings.Contains(got, "Effective") {
+		t.Errorf("effective dates without --show-effective-dates:\n%s", got)
+	}
+}
+
+func TestBenchmarkNoneOverridesTheBenchmark(t *testing.T) {
+	args := []string{"-s", "AAPL", "-f", "2020-01-01", "-t", "2020-06-30", "--today", "2021-01-04", "--benchmark", "MSFT"}
+
+	out, stderr, code := runMain(t, args...)
+	if code != ExitOK || !strings.Contains(out, "Benchmark      : MSFT\n") {
+		t.Fatalf("exit code %d without a benchmark in:\n%s%s", code, out, stderr)
+	}
+
+	out, stderr, code = runMain(t, append(args, "--benchmark-none")...)
+	if code != ExitOK || strings.Contains(out, "Benchmark") {
+		t.Errorf("exit code %d with a benchmark in:\n%s%s", code, out, stderr)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Market is a NASDAQ market with its own historical data endpoint and
+// response format.
+type Market interface {
+	Name() string
+	// HistoricalURL returns the URL of the trading data of a ticker between
+	// two ISO dates.
+	HistoricalURL(ticker, fromDate, toDate string) string
+	// ParseHistorical parses a response from HistoricalURL into the shape
+	// the US market uses, with rows newest first.
+	ParseHistorical(data []byte) (*NASDAQHistoricalAPIResponse, error)
+}
+
+// Market all historical data is fetched from.
+var market Market = USMarket{}
+
+func Markets() []string {
+	return []string{"us", "nordic"}
+}
+
+// MarketByName returns the market called name, parsing trading dates with
+// dateLayout if given.
+func MarketByName(name, dateLayout string) (Market, error) {
+	switch strings.ToLower(name) {
+	case "us":
+		return USMarket{DateLayout: dateLayout}, nil
+	case "nordic":
+		return NordicMarket{DateLayout: dateLayout}, nil
+	}
+	return nil, fmt.Errorf("unknown market '%s', expected one of: %s", name, strings.Join(Markets(), ", "))
+}
+
+// USMarket is NASDAQ US, the default. Trading dates are expected as
+// MM/DD/YYYY unless DateLayout is set, with other known layouts tried before
+// failing.
+type USMarket struct {
+	DateLayout string
+}
+
+func (USMarket) Name() string {
+	return "us"
+}
+
+func (USMarket) HistoricalURL(ticker, fromDate, toDate string) string {
+	url := nasdaqHistoricalURL
+
+	url = strings.Replace(url, "{ticker}", strings.ToUpper(ticker), 1)
+	url = strings.Replace(url, "{fromDate}", fromDate, 1)
+	url = strings.Replace(url, "{toDate}", toDate, 1)
+
+	return url
+}
+
+func (um USMarket) ParseHistorical(data []byte) (*NASDAQHistoricalAPIResponse, error) {
+	ndr := new(NASDAQHistoricalAPIResponse)
+	err := json.Unmarshal(data, ndr)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ndr.NormalizeDates(um.DateLayout)
+	if err != nil {
+		return nil, err
+	}
+
+	return ndr, nil
+}
+
+var nasdaqNordicHistoricalURL = "https://api.nasdaq.com/api/nordic/instruments/{ticker}/chart/download?assetClass=SHARES&fromDate={fromDate}&toDate={toDate}"
+
+// NordicMarket is NASDAQ Nordic and Baltic, covering the Stockholm,
+// Helsinki, Copenhagen, Iceland, Tallinn, Riga and Vilnius exchanges.
+// Tickers are instrument IDs, e.g. SSE3966 for Volvo B, and prices are in the
+// instrument's own currency. Trading dates are expected as YYYY-MM-DD unless
+// DateLayout is set.
+type NordicMarket struct {
+	DateLayout string
+}
+
+func (NordicMarket) Name() string {
+	return "nordic"
+}
+
+func (NordicMarket) HistoricalURL(ticker, fromDate, toDate string) string {
+	url := nasdaqNordicHistoricalURL
+
+	url = strings.Replace(url, "{ticker}", strings.ToUpper(ticker), 1)
+	url = strings.Replace(url, "{fromDate}", fromDate, 1)
+	url = strings.Replace(url, "{toDate}", toDate, 1)
+
+	return url
+}
+
+type nordicHistoricalAPIResponse struct {
+	Data struct {
+		InstrumentID string `json:"instrumentId"`
+		Charts       struct {
+			Rows []struct {
+				DateTime    string `json:"dateTime"`
+				ClosePrice  string `json:"closePrice"`
+				OpenPrice   string `json:"openPrice"`
+				HighPrice   string `json:"highPrice"`
+				LowPrice    string `json:"lowPrice"`
+				TotalVolume string `json:"totalVolume"`
+			} `json:"rows"`
+		} `json:"charts"`
+	} `json:"data"`
+}
+
+// ParseHistorical converts the Nordic rows into US rows dated MM/DD/YYYY.
+func (nm NordicMarket) ParseHistorical(data []byte) (*NASDAQHistoricalAPIResponse, error) {
+	nr := new(nordicHistoricalAPIResponse)
+	err := json.Unmarshal(data, nr)
+	if err != nil {
+		return nil, err
+	}
+
+	ndr := new(NASDAQHistoricalAPIResponse)
+	ndr.Data.Symbol = nr.Data.InstrumentID
+
+	for _, r := range nr.Data.Charts.Rows {
+		layouts := []string{"2006-01-02"}
+		if nm.DateLayout != "" {
+			layouts = []string{nm.DateLayout, "2006-01-02"}
+		}
+		t, err := ParseNASDAQDate(r.DateTime, layouts...)
+		if err != nil {
+			return nil, err
+		}
+
+		ndr.Data.TradesTable.Rows = append(ndr.Data.TradesTable.Rows, &TradingData{
+			Date:   t.Format(nasdaqDateLayout),
+			Close:  r.ClosePrice,
+			Open:   r.OpenPrice,
+			High:   r.HighPrice,
+			Low:    r.LowPrice,
+			Volume: r.TotalVolume,
+		})
+	}
+
+	rows := ndr.Data.TradesTable.Rows
+	sort.Slice(rows, func(i, j int) bool {
+		return NASDAQDateToTime(rows[i].Date).After(NASDAQDateToTime(rows[j].Date))
+	})
+	ndr.Data.TotalRecords = int64(len(rows))
+
+	return ndr, nil
+}
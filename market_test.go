@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseNordicHistorical(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "nordic.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ndr, err := NordicMarket{}.ParseHistorical(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ndr.Data.Symbol != "SSE3966" || ndr.Data.TotalRecords != 3 {
+		t.Errorf("%s with %d records, want SSE3966 with 3", ndr.Data.Symbol, ndr.Data.TotalRecords)
+	}
+	// Newest first, dated like the US market
+	for i, want := range []string{"01/07/2020", "01/03/2020", "01/02/2020"} {
+		if got := ndr.Data.TradesTable.Rows[i].Date; got != want {
+			t.Errorf("row %d dated %s, want %s", i, got, want)
+		}
+	}
+	if p, err := ParseUSD(ndr.Data.TradesTable.Rows[0].Close); err != nil || p != 151.45 {
+		t.Errorf("latest close %v, %v, want 151.45", p, err)
+	}
+}
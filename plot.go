@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	plotWidth         = 800
+	plotHeight        = 400
+	plotMargin        = 60
+	plotInvestedColor = "#999999"
+	plotValueColor    = "#1f77b4"
+)
+
+// PlotSVG writes a line chart of the money invested and the value of the
+// series over time to an SVG file.
+func PlotSVG(file, title string, series []ValuePoint) error {
+	if len(series) == 0 {
+		return fmt.Errorf("nothing to plot")
+	}
+
+	from, to := series[0].Date, series[len(series)-1].Date
+	var max float64
+	for _, vp := range series {
+		if vp.Value > max {
+			max = vp.Value
+		}
+		if vp.Invested > max {
+			max = vp.Invested
+		}
+	}
+	if max == 0 {
+		max = 1
+	}
+
+	x := func(t time.Time) float64 {
+		if !to.After(from) {
+			return plotMargin
+		}
+		return plotMargin + float64(t.Sub(from))/float64(to.Sub(from))*(plotWidth-2*plotMargin)
+	}
+	y := func(v float64) float64 {
+		return plotHeight - plotMargin - v/max*(plotHeight-2*plotMargin)
+	}
+
+	var invested, value []string
+	for _, vp := range series {
+		invested = append(invested, fmt.Sprintf("%.1f,%.1f", x(vp.Date), y(vp.Invested)))
+		value = append(value, fmt.Sprintf("%.1f,%.1f", x(vp.Date), y(vp.Value)))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="12">`+"\n", plotWidth, plotHeight)
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="white"/>`+"\n")
+	fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="16">%s</text>`+"\n", plotMargin, plotMargin/2, escapeXML(title))
+	fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="black"/>`+"\n", plotMargin, plotHeight-plotMargin, plotWidth-plotMargin, plotHeight-plotMargin)
+	fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="black"/>`+"\n", plotMargin, plotMargin, plotMargin, plotHeight-plotMargin)
+	fmt.Fprintf(&b, `<text x="%d" y="%d">%s</text>`+"\n", plotMargin, plotHeight-plotMargin+20, from.Format("2006-01-02"))
+	fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end">%s</text>`+"\n", plotWidth-plotMargin, plotHeight-plotMargin+20, to.Format("2006-01-02"))
+	fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end">%s</text>`+"\n", plotMargin-5, plotMargin+4, escapeXML(Money(max)))
+	fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end">%s</text>`+"\n", plotMargin-5, plotHeight-plotMargin+4, escapeXML(Money(0)))
+	fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="2" points="%s"/>`+"\n", plotInvestedColor, strings.Join(invested, " "))
+	fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="2" points="%s"/>`+"\n", plotValueColor, strings.Join(value, " "))
+	fmt.Fprintf(&b, `<text x="%d" y="%d" fill="%s">Invested</text>`+"\n", plotWidth-plotMargin-120, plotMargin/2, plotInvestedColor)
+	fmt.Fprintf(&b, `<text x="%d" y="%d" fill="%s">Value</text>`+"\n", plotWidth-plotMargin-50, plotMargin/2, plotValueColor)
+	b.WriteString("</svg>\n")
+
+	return os.WriteFile(file, []byte(b.String()), 0644)
+}
+
+func escapeXML(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;").Replace(s)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestPlotSVGHasAPointPerValue(t *testing.T) {
+	source := testSource{"X": weekdayRows("2020-01-01", "2020-12-31", flatPrice(10))}
+	dp := NewDCAPortfolio([]SymbolSpec{{Symbol: "X"}}, "2020-01-01", "2020-12-31", Monthly, 100, Options{Source: source})
+	series := dp.ValueSeries()
+
+	file := filepath.Join(t.TempDir(), "plot.svg")
+	err := PlotSVG(file, "X", series)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svg, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	polylines := regexp.MustCompile(`<polyline [^>]*points="([^"]*)"`).FindAllStringSubmatch(string(svg), -1)
+	if len(polylines) != 2 {
+		t.Fatalf("%d polylines, want invested and value", len(polylines))
+	}
+	for _, p := range polylines {
+		if got := len(strings.Fields(p[1])); got != len(series) {
+			t.Errorf("%d points, want one for each of the %d values", got, len(series))
+		}
+	}
+}
+
+func TestPlotSVGWithoutValues(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "plot.svg")
+	if err := PlotSVG(file, "X", nil); err == nil {
+		t.Error("no error plotting nothing")
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Error("created a file plotting nothing")
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import "fmt"
+
+type SymbolCheck struct {
+	Symbol      string
+	TradingDays int
+	Err         error
+}
+
+func (sc SymbolCheck) OK() bool {
+	return sc.Err == nil
+}
+
+// CheckSymbols fetches the trading data of every symbol for its dates before
+// a run, so that unknown tickers are reported up front instead of failing
+// the run part of the way through. The data fetched is cached for the run.
+func CheckSymbols(specs []SymbolSpec, fromDate, toDate string, opts Options) []SymbolCheck {
+	var checks []SymbolCheck
+
+	for _, ss := range DedupeSymbolSpecs(specs) {
+		from, to := fromDate, toDate
+		if ss.From != "" {
+			from = ss.From
+		}
+		if ss.To != "" {
+			to = ss.To
+		}
+		checks = append(checks, checkSymbol(ss.Symbol, from, to, opts.source()))
+	}
+
+	return checks
+}
+
+func checkSymbol(symbol, fromDate, toDate string, source DataSource) (sc SymbolCheck) {
+	sc.Symbol = symbol
+
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
+				sc.Err = e
+			} else {
+				sc.Err = fmt.Errorf("%v", r)
+			}
+		}
+	}()
+
+	nd, err := source.HistoricalData(symbol, fromDate, toDate)
+	if err != nil {
+		sc.Err = err
+		return sc
+	}
+
+	sc.TradingDays = len(nd.WithoutMissingPrices().Data.TradesTable.Rows)
+	if sc.TradingDays == 0 {
+		sc.Err = fmt.Errorf("%w for %s between %s and %s", ErrNoTradingData, symbol, fromDate, toDate)
+	}
+
+	return sc
+}
+
+func PrintSymbolChecks(checks []SymbolCheck) {
+	for _, sc := range checks {
+		if sc.OK() {
+			printer.Fprintf(output, "ok   %-8s %d trading days\n", sc.Symbol, sc.TradingDays)
+		} else {
+			printer.Fprintf(output, "bad  %-8s %v\n", sc.Symbol, sc.Err)
+		}
+	}
+	printer.Fprintf(output, "\n")
+}
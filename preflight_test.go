@@ -0,0 +1,39 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckSymbolsFlagsABadSymbol(t *testing.T) {
+	source := testSource{"GOOD": weekdayRows("2020-01-01", "2020-01-31", flatPrice(10))}
+	specs := []SymbolSpec{{Symbol: "GOOD"}, {Symbol: "BAD"}}
+
+	checks := CheckSymbols(specs, "2020-01-01", "2020-01-31", Options{Source: source})
+
+	if len(checks) != 2 || !checks[0].OK() || checks[0].TradingDays != 23 {
+		t.Fatalf("checks %+v, want GOOD with 23 trading days", checks)
+	}
+	if checks[1].OK() || ExitCode(checks[1].Err) != ExitNoData {
+		t.Errorf("BAD checked with error %v, want no trading data", checks[1].Err)
+	}
+
+	got := captureOutput(t, func() { PrintSymbolChecks(checks) })
+	if !strings.Contains(got, "ok   GOOD     23 trading days\n") || !strings.Contains(got, "bad  BAD      no trading data for BAD\n") {
+		t.Errorf("printed:\n%s", got)
+	}
+}
+
+func TestValidateSymbolsExitCode(t *testing.T) {
+	args := []string{"-s", "AAPL,NOPE", "-f", "2020-01-01", "-t", "2020-03-31", "--today", "2021-01-04", "--validate-symbols"}
+
+	out, _, code := runMain(t, args...)
+	if code == ExitOK || !strings.Contains(out, "bad  NOPE") {
+		t.Errorf("exit code %d with a bad symbol:\n%s", code, out)
+	}
+
+	out, stderr, code := runMain(t, append(args, "--continue-on-error")...)
+	if code != ExitOK || !strings.Contains(out, "Symbol         : AAPL\n") || strings.Contains(out, "Symbol         : NOPE\n") {
+		t.Errorf("exit code %d continuing on the error:\n%s%s", code, out, stderr)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PriceWeights weighs the open, high, low and close prices of a trading day
+// into a single price.
+type PriceWeights struct {
+	Open  float64
+	High  float64
+	Low   float64
+	Close float64
+}
+
+var (
+	// The average of all four prices, the default.
+	OHLCPrice = PriceWeights{Open: 1, High: 1, Low: 1, Close: 1}
+	// The typical price, (high + low + close) / 3.
+	TypicalPrice = PriceWeights{High: 1, Low: 1, Close: 1}
+	// The weighted close, (high + low + 2 * close) / 4.
+	WeightedClosePrice = PriceWeights{High: 1, Low: 1, Close: 2}
+	// The median price, (high + low) / 2.
+	MedianPrice = PriceWeights{High: 1, Low: 1}
+	ClosePrice  = PriceWeights{Close: 1}
+)
+
+var priceBases = map[string]PriceWeights{
+	"ohlc":           OHLCPrice,
+	"typical":        TypicalPrice,
+	"weighted-close": WeightedClosePrice,
+	"median":         MedianPrice,
+	"close":          ClosePrice,
+}
+
+// Weights used for the price of a trading day, see AvgPrice.
+var priceBasis = OHLCPrice
+
+// ParsePriceWeights parses the name of a price basis, one of ohlc, typical,
+// weighted-close, median or close, or custom weights for the open, high, low
+// and close prices, e.g. "0,1,1,2".
+func ParsePriceWeights(s string) (PriceWeights, error) {
+	if pw, ok := priceBases[strings.ToLower(strings.TrimSpace(s))]; ok {
+		return pw, nil
+	}
+
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return PriceWeights{}, fmt.Errorf("unknown price basis '%s', expected ohlc, typical, weighted-close, median, close or open,high,low,close weights", s)
+	}
+
+	var weights [4]float64
+	var sum float64
+	for i, p := range parts {
+		w, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil || w < 0 {
+			return PriceWeights{}, fmt.Errorf("invalid price weight '%s' in '%s'", p, s)
+		}
+		weights[i] = w
+		sum += w
+	}
+	if sum == 0 {
+		return PriceWeights{}, fmt.Errorf("price weights '%s' are all zero", s)
+	}
+
+	return PriceWeights{Open: weights[0], High: weights[1], Low: weights[2], Close: weights[3]}, nil
+}
+
+// WeightedPrice returns the prices of the trading day weighted by pw. Missing
+// and invalid prices are left out, weighing the others up accordingly.
+// Returns 0 if none of the weighted prices are valid.
+func (t *TradingData) WeightedPrice(pw PriceWeights) float64 {
+	var sum, weights float64
+
+	for _, p := range []struct {
+		value  string
+		weight float64
+	}{
+		{t.Open, pw.Open},
+		{t.High, pw.High},
+		{t.Low, pw.Low},
+		{t.Close, pw.Close},
+	} {
+		if p.weight == 0 {
+			continue
+		}
+		v, err := ParseUSD(p.value)
+		if err != nil {
+			continue
+		}
+		sum += v * p.weight
+		weights += p.weight
+	}
+
+	if weights == 0 {
+		return 0
+	}
+	return sum / weights
+}
+
+// TypicalPrice returns (high + low + close) / 3, a common proxy for the
+// volume weighted average price.
+func (t *TradingData) TypicalPrice() float64 {
+	return t.WeightedPrice(TypicalPrice)
+}
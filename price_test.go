@@ -0,0 +1,69 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMissingHighPrice(t *testing.T) {
+	td := &TradingData{Date: "01/03/2020", Open: "$10.00", High: "N/A", Low: "$8.00", Close: "$12.00", Volume: "N/A"}
+
+	if !td.HasPrice() {
+		t.Error("no price with only the high missing")
+	}
+	if got := td.WeightedPrice(OHLCPrice); got != 10 {
+		t.Errorf("OHLC price %v, want 10 averaging the prices present", got)
+	}
+	if got := td.WeightedPrice(MedianPrice); got != 8 {
+		t.Errorf("median price %v, want the low 8 without the high", got)
+	}
+	if got := td.WeightedPrice(PriceWeights{High: 1}); got != 0 {
+		t.Errorf("high price %v, want 0 for missing", got)
+	}
+
+	empty := &TradingData{Date: "01/02/2020", Open: "N/A", High: "", Low: " n/a ", Close: "$"}
+	if empty.HasPrice() {
+		t.Error("a price for a row with all prices missing")
+	}
+
+	ndr := &NASDAQHistoricalAPIResponse{}
+	ndr.Data.TradesTable.Rows = []*TradingData{td, empty}
+	if rows := ndr.WithoutMissingPrices().Data.TradesTable.Rows; len(rows) != 1 || rows[0] != td {
+		t.Errorf("rows %v, want only the one with prices", rows)
+	}
+}
+
+func TestPriceWeightings(t *testing.T) {
+	td := &TradingData{Date: "01/03/2020", Open: "$11.00", High: "$14.00", Low: "$8.00", Close: "$12.00"}
+
+	for _, tc := range []struct {
+		basis string
+		want  float64
+	}{
+		{"ohlc", (11 + 14 + 8 + 12) / 4.0},
+		{"typical", (14 + 8 + 12) / 3.0},
+		{"weighted-close", (14 + 8 + 2*12) / 4.0},
+		{"median", (14 + 8) / 2.0},
+		{"close", 12},
+		{"0,1,1,2", (14 + 8 + 2*12) / 4.0},
+		{"1, 0, 0, 3", (11 + 3*12) / 4.0},
+	} {
+		pw, err := ParsePriceWeights(tc.basis)
+		if err != nil {
+			t.Errorf("%s: %v", tc.basis, err)
+			continue
+		}
+		if got := td.WeightedPrice(pw); math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("%s price %v, want %v", tc.basis, got, tc.want)
+		}
+	}
+	if got := td.TypicalPrice(); math.Abs(got-34.0/3) > 1e-9 {
+		t.Errorf("typical price %v, want %v", got, 34.0/3)
+	}
+
+	for _, basis := range []string{"vwap", "1,1,1", "1,-1,1,1", "0,0,0,0"} {
+		if _, err := ParsePriceWeights(basis); err == nil {
+			t.Errorf("no error for price basis %q", basis)
+		}
+	}
+}
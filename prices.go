@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// WritePricesCSV writes the trading days of ndr oldest first with date as
+// YYYY-MM-DD, open, high, low, close and volume columns, the prices as plain
+// numbers and missing values left empty.
+func WritePricesCSV(w io.Writer, ndr *NASDAQHistoricalAPIResponse) error {
+	cw := csv.NewWriter(w)
+
+	cw.Write([]string{"date", "open", "high", "low", "close", "volume"})
+
+	for _, r := range RowsAscending(ndr.Data.TradesTable.Rows) {
+		rec := []string{NASDAQDateToTime(r.Date).Format("2006-01-02")}
+		for _, p := range []string{r.Open, r.High, r.Low, r.Close} {
+			v, err := ParseUSD(p)
+			if err == ErrMissingValue {
+				rec = append(rec, "")
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("%s on %s: %w", ndr.Data.Symbol, r.Date, err)
+			}
+			rec = append(rec, strconv.FormatFloat(v, 'f', -1, 64))
+		}
+		rec = append(rec, normalizeVolume(r.Volume))
+		cw.Write(rec)
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// normalizeVolume drops the thousands separators of a volume, or returns ""
+// if it's missing.
+func normalizeVolume(volume string) string {
+	volume = strings.ReplaceAll(strings.TrimSpace(volume), ",", "")
+	if _, err := strconv.ParseInt(volume, 10, 64); err != nil {
+		return ""
+	}
+	return volume
+}
+
+// pricesMain runs the prices subcommand: nasdaq prices [flags] <SYMBOL>
+func pricesMain(args []string) {
+	fs := pflag.NewFlagSet("prices", pflag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s prices [flags] <SYMBOL>\n\nPrints the daily prices of the symbol as CSV, oldest first, without running a DCA.\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fromDate := fs.StringP("from", "f", "2008-01-01", "Print prices from this date")
+	toDate := fs.StringP("to", "t", Today(), "Print prices up to this date")
+	dataDir := fs.String("data-dir", "", "Read trading data from <SYMBOL>.csv files in this directory instead of the NASDAQ API")
+	outputFile := fs.StringP("output", "O", "", "Write the CSV to this file instead of stdout, creating its directory if needed")
+
+	err := fs.Parse(args)
+	if err == pflag.ErrHelp {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		fs.Usage()
+		exit(ExitUsage)
+	}
+
+	handleInterrupts()
+	if fs.NArg() != 1 {
+		fs.Usage()
+		exit(ExitUsage)
+	}
+
+	err = ValidateDateRange(*fromDate, *toDate)
+	if err != nil {
+		panic(err)
+	}
+
+	var source DataSource = NASDAQSource{Cache: &FileCache{Dir: "."}, FailOnEmpty: true, RefreshIncomplete: true}
+	if *dataDir != "" {
+		source = &CSVDirSource{Dir: *dataDir}
+	}
+
+	ndr, err := source.HistoricalData(strings.ToUpper(fs.Arg(0)), *fromDate, *toDate)
+	if err != nil {
+		panic(err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *outputFile != "" {
+		f, err := CreateOutputFile(*outputFile)
+		if err != nil {
+			panic(err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	err = WritePricesCSV(w, ndr.WithoutMissingPrices())
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPricesCSVColumnsAndRows(t *testing.T) {
+	ndr, _ := testSource{"X": weekdayRows("2020-01-01", "2020-01-31", func(t time.Time) float64 { return float64(t.Day()) })}.HistoricalData("X", "2020-01-01", "2020-01-31")
+	ndr.Data.TradesTable.Rows[0].High = "N/A"
+	ndr.Data.TradesTable.Rows[0].Volume = "12,345,678"
+
+	var sb strings.Builder
+	err := WritePricesCSV(&sb, ndr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(strings.NewReader(sb.String())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(records[0], ",") != "date,open,high,low,close,volume" {
+		t.Errorf("columns %v", records[0])
+	}
+	if len(records) != 1+23 {
+		t.Fatalf("%d records, want a header and the 23 trading days", len(records))
+	}
+	if got := strings.Join(records[1], ","); got != "2020-01-01,1,1,1,1,1000" {
+		t.Errorf("first row %s, want the oldest trading day", got)
+	}
+	// The newest with its high missing and its volume without separators
+	if got := strings.Join(records[23], ","); got != "2020-01-31,31,,31,31,12345678" {
+		t.Errorf("last row %s", got)
+	}
+}
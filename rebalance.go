@@ -0,0 +1,100 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// runRebalanced steps through the purchases of all runs in date order and,
+// after each purchase date, rebalances the positions back to their target
+// weights if any of them has drifted more than threshold (a fraction, e.g.
+// 0.05 for ±5 percentage points) away from its target. Returns the number
+// of rebalances.
+func runRebalanced(runs []*dcaRun, weights []float64, threshold float64) (rebalances int) {
+	for {
+		var t time.Time
+		for _, r := range runs {
+			if r.pending() && (t.IsZero() || r.at.Before(t)) {
+				t = r.at
+			}
+		}
+		if t.IsZero() {
+			return rebalances
+		}
+
+		for _, r := range runs {
+			if r.pending() && r.at.Equal(t) {
+				r.purchase()
+			}
+		}
+
+		if rebalance(runs, weights, t, threshold) {
+			rebalances++
+		}
+	}
+}
+
+// rebalance rebalances the positions that have been bought into if any of
+// them has drifted beyond the threshold. Positions that haven't started yet,
+// e.g. because of a later inception, are left out and the remaining target
+// weights are scaled up to sum to 1. The fees on the trades are taken out of
+// the total rebalanced.
+func rebalance(runs []*dcaRun, weights []float64, t time.Time, threshold float64) bool {
+	prices := make([]float64, len(runs))
+	var total, totalWeight float64
+
+	for i, r := range runs {
+		if r.d.Units == 0 {
+			continue
+		}
+		prices[i] = r.priceAt(t)
+		total += r.d.Units * prices[i]
+		totalWeight += weights[i]
+	}
+	if total <= 0 {
+		return false
+	}
+
+	drifted := false
+	for i, r := range runs {
+		if r.d.Units == 0 {
+			continue
+		}
+		if math.Abs(r.d.Units*prices[i]/total-weights[i]/totalWeight) > threshold {
+			drifted = true
+			break
+		}
+	}
+	if !drifted {
+		return false
+	}
+
+	fees := make([]float64, len(runs))
+	var totalFees float64
+	for i, r := range runs {
+		if r.d.Units == 0 || prices[i] <= 0 {
+			continue
+		}
+		fees[i] = math.Abs(total*weights[i]/totalWeight-r.d.Units*prices[i]) * r.opts.FeePercent / 100
+		totalFees += fees[i]
+	}
+
+	for i, r := range runs {
+		if r.d.Units == 0 || prices[i] <= 0 {
+			continue
+		}
+		units := (total - totalFees) * weights[i] / totalWeight / prices[i]
+		r.d.Transactions = append(r.d.Transactions, Transaction{
+			Date:      r.tradedOn(t),
+			Price:     prices[i],
+			Amount:    (units - r.d.Units) * prices[i],
+			Units:     units - r.d.Units,
+			Rebalance: true,
+			Fee:       fees[i],
+		})
+		r.d.Units = units
+		r.d.Fees += fees[i]
+	}
+
+	return true
+}
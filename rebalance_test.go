@@ -0,0 +1,83 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRebalanceWhenDriftCrossesTheBand(t *testing.T) {
+	// Y doubles once in February, drifting to 62.5% of the portfolio by the
+	// March purchase
+	source := testSource{
+		"X": weekdayRows("2020-01-01", "2020-06-30", flatPrice(100)),
+		"Y": weekdayRows("2020-01-01", "2020-06-30", func(t time.Time) float64 {
+			if t.Before(ISODateToTime("2020-02-15")) {
+				return 100
+			}
+			return 200
+		}),
+	}
+	opts := Options{Source: source, RebalanceThreshold: 10}
+
+	dp := NewDCAPortfolio([]SymbolSpec{{Symbol: "X"}, {Symbol: "Y"}}, "2020-01-01", "2020-06-30", Monthly, 100, opts)
+
+	if dp.Rebalances != 1 {
+		t.Fatalf("%d rebalances, want 1", dp.Rebalances)
+	}
+	for _, d := range dp.Positions {
+		var rebalanced []Transaction
+		for _, tx := range d.Transactions {
+			if tx.Rebalance {
+				rebalanced = append(rebalanced, tx)
+			}
+		}
+		if len(rebalanced) != 1 || rebalanced[0].Date.Format("2006-01-02") != "2020-03-02" {
+			t.Errorf("%s rebalanced %v, want once on 2020-03-02", d.Symbol, rebalanced)
+		}
+	}
+	if x, y := dp.Positions[0].TotalReturn, dp.Positions[1].TotalReturn; math.Abs(x-y) > 1e-6 {
+		t.Errorf("positions worth %v and %v, want equal with the purchases after the rebalance split evenly", x, y)
+	}
+
+	// The same drift stays within a wider band
+	opts.RebalanceThreshold = 15
+	if dp := NewDCAPortfolio([]SymbolSpec{{Symbol: "X"}, {Symbol: "Y"}}, "2020-01-01", "2020-06-30", Monthly, 100, opts); dp.Rebalances != 0 {
+		t.Errorf("%d rebalances within a 15 point band, want none", dp.Rebalances)
+	}
+}
+
+func TestRebalanceSellFees(t *testing.T) {
+	source := testSource{
+		"X": weekdayRows("2020-01-01", "2020-06-30", flatPrice(100)),
+		"Y": weekdayRows("2020-01-01", "2020-06-30", func(t time.Time) float64 {
+			if t.Before(ISODateToTime("2020-02-15")) {
+				return 100
+			}
+			return 200
+		}),
+	}
+	opts := Options{Source: source, RebalanceThreshold: 10, FeePercent: 1}
+
+	dp := NewDCAPortfolio([]SymbolSpec{{Symbol: "X"}, {Symbol: "Y"}}, "2020-01-01", "2020-06-30", Monthly, 100, opts)
+
+	var sellFees, fees float64
+	for _, d := range dp.Positions {
+		for _, tx := range d.Transactions {
+			fees += tx.Fee
+			if tx.Units < 0 {
+				sellFees += tx.Fee
+				// Charged on the trade before the fees are taken out
+				if want := -tx.Amount * 0.01; math.Abs(tx.Fee-want) > want*0.05 {
+					t.Errorf("%s sold %v with a fee of %v, want about 1 %% of it", d.Symbol, -tx.Amount, tx.Fee)
+				}
+			}
+		}
+	}
+	if sellFees <= 0 {
+		t.Fatal("no fees on the rebalancing sells")
+	}
+	if !approx(dp.TotalFees, fees) {
+		t.Errorf("total fees %v, want the %v of all buys and sells", dp.TotalFees, fees)
+	}
+}
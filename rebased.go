@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// RebasedPoint is the value of the portfolio and its benchmark on a date,
+// both rebased to 100 on the start date.
+type RebasedPoint struct {
+	Date      time.Time
+	Portfolio float64
+	Benchmark float64
+}
+
+// RebasedSeries returns the portfolio's and the benchmark's values on every
+// trading day of the portfolio rebased to 100 on the start date, with the
+// money invested taken out like AnnualizedVolatility, so that the two
+// overlay on a chart however much each had invested.
+func (dp *DCAPortfolio) RebasedSeries() []RebasedPoint {
+	series := dp.DailyValueSeries()
+	dates := make([]time.Time, len(series))
+	for i, vp := range series {
+		dates[i] = vp.Date
+	}
+
+	var benchmark []ValuePoint
+	if dp.BlendedBenchmark != nil {
+		benchmark = dp.BlendedBenchmark.valuesOn(dates)
+	} else {
+		benchmark = dp.Benchmark.valueSeries(dates)
+	}
+
+	pi, bi := rebase(series), rebase(benchmark)
+	points := make([]RebasedPoint, len(dates))
+	for i, date := range dates {
+		points[i] = RebasedPoint{Date: date, Portfolio: pi[i], Benchmark: bi[i]}
+	}
+
+	return points
+}
+
+// rebase returns an index of the value of series starting at 100, moving
+// with its returns between the points. Points without a value, e.g. on days
+// missing prices, carry the index of the point before them.
+func rebase(series []ValuePoint) []float64 {
+	index := make([]float64, len(series))
+	v := 100.0
+	last := -1
+	for i, vp := range series {
+		if vp.Value > 0 {
+			if last >= 0 {
+				prev := series[last]
+				v *= (vp.Value - (vp.Invested - prev.Invested)) / prev.Value
+			}
+			last = i
+		}
+		index[i] = v
+	}
+	return index
+}
+
+// WriteRebasedCSV writes the points with date, portfolio and benchmark
+// columns.
+func WriteRebasedCSV(w io.Writer, points []RebasedPoint) error {
+	cw := csv.NewWriter(w)
+
+	cw.Write([]string{"date", "portfolio", "benchmark"})
+	for _, p := range points {
+		cw.Write([]string{
+			p.Date.Format("2006-01-02"),
+			strconv.FormatFloat(p.Portfolio, 'f', 4, 64),
+			strconv.FormatFloat(p.Benchmark, 'f', 4, 64),
+		})
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
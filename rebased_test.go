@@ -0,0 +1,48 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRebasedSeriesStartAt100OnTheSameDates(t *testing.T) {
+	source := testSource{
+		"X": weekdayRows("2020-01-01", "2020-06-30", func(t time.Time) float64 { return 10 + float64(t.YearDay())/10 }),
+		"B": weekdayRows("2020-01-01", "2020-06-30", flatPrice(50)),
+	}
+	dp := NewDCAPortfolio([]SymbolSpec{{Symbol: "X"}}, "2020-01-01", "2020-06-30", Monthly, 100, Options{Source: source, Benchmark: "B"})
+
+	points := dp.RebasedSeries()
+	series := dp.DailyValueSeries()
+	if len(points) != len(series) || len(points) == 0 {
+		t.Fatalf("%d rebased points, want one on each of the %d days valued", len(points), len(series))
+	}
+	if points[0].Portfolio != 100 || points[0].Benchmark != 100 {
+		t.Errorf("starting at %v and %v, want both at 100", points[0].Portfolio, points[0].Benchmark)
+	}
+	for i, p := range points {
+		if !p.Date.Equal(series[i].Date) {
+			t.Fatalf("point %d on %s, want %s", i, p.Date.Format("2006-01-02"), series[i].Date.Format("2006-01-02"))
+		}
+	}
+
+	// The contributions taken out, the index moves with the price alone, up
+	// to the last price the position is valued at
+	last := points[len(points)-1]
+	want := 100 * dp.Positions[0].LastPrice / (10 + float64(series[0].Date.YearDay())/10)
+	if math.Abs(last.Portfolio-want) > 1e-6 || math.Abs(last.Benchmark-100) > 1e-9 {
+		t.Errorf("ending at %v and %v, want %v and the flat benchmark at 100", last.Portfolio, last.Benchmark, want)
+	}
+
+	var sb strings.Builder
+	err := WriteRebasedCSV(&sb, points)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(sb.String()), "\n")
+	if len(lines) != len(points)+1 || lines[0] != "date,portfolio,benchmark" || !strings.HasSuffix(lines[1], ",100.0000,100.0000") {
+		t.Errorf("rebased CSV starting with:\n%s", strings.Join(lines[:2], "\n"))
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var (
+	isinPattern  = regexp.MustCompile(`^[A-Z]{2}[A-Z0-9]{9}[0-9]$`)
+	cusipPattern = regexp.MustCompile(`^[A-Z0-9]{8}[0-9]$`)
+)
+
+func IsISIN(id string) bool {
+	return isinPattern.MatchString(strings.ToUpper(id))
+}
+
+func IsCUSIP(id string) bool {
+	return cusipPattern.MatchString(strings.ToUpper(id))
+}
+
+// SymbolResolver maps CUSIP and ISIN identifiers to NASDAQ tickers.
+type SymbolResolver struct {
+	tickers map[string]string
+}
+
+// LoadSymbolResolver reads a CSV mapping file with two columns, the CUSIP or
+// ISIN identifier followed by its ticker, e.g. "US0378331005,AAPL". An
+// optional header row is skipped.
+func LoadSymbolResolver(file string) (*SymbolResolver, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cr := csv.NewReader(f)
+	cr.FieldsPerRecord = 2
+	cr.TrimLeadingSpace = true
+	cr.Comment = '#'
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not read symbol mapping file %s: %w", file, err)
+	}
+
+	sr := &SymbolResolver{tickers: make(map[string]string)}
+
+	for i, rec := range records {
+		id := strings.ToUpper(strings.TrimSpace(rec[0]))
+		ticker := strings.ToUpper(strings.TrimSpace(rec[1]))
+		if !IsISIN(id) && !IsCUSIP(id) {
+			if i == 0 {
+				continue // Header
+			}
+			return nil, fmt.Errorf("symbol mapping file %s: '%s' is not a CUSIP or ISIN", file, rec[0])
+		}
+		sr.tickers[id] = ticker
+	}
+
+	return sr, nil
+}
+
+// Resolve returns the ticker for a CUSIP or ISIN. Anything else is assumed
+// to already be a ticker and is returned unchanged.
+func (sr *SymbolResolver) Resolve(symbol string) (string, error) {
+	id := strings.ToUpper(symbol)
+	if !IsISIN(id) && !IsCUSIP(id) {
+		return symbol, nil
+	}
+
+	ticker, ok := sr.tickers[id]
+	if !ok {
+		return "", fmt.Errorf("no ticker found for identifier %s", symbol)
+	}
+
+	return ticker, nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveISINFromMappingFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "symbols.csv")
+	writeFile(t, file, "identifier,ticker\nUS0378331005,aapl\n594918104,MSFT\n")
+
+	sr, err := LoadSymbolResolver(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for symbol, want := range map[string]string{
+		"US0378331005": "AAPL",
+		"us0378331005": "AAPL",
+		"594918104":    "MSFT",
+		"TSLA":         "TSLA",
+	} {
+		got, err := sr.Resolve(symbol)
+		if err != nil || got != want {
+			t.Errorf("Resolve(%s) = %s, %v, want %s", symbol, got, err, want)
+		}
+	}
+
+	if _, err := sr.Resolve("US5949181045"); err == nil {
+		t.Error("no error for an ISIN missing from the mapping file")
+	}
+}
+
+func TestLoadSymbolResolverRejectsBadIdentifiers(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "symbols.csv")
+	writeFile(t, file, "US0378331005,AAPL\nAPPLE,AAPL\n")
+
+	if _, err := LoadSymbolResolver(file); err == nil {
+		t.Error("no error for a row that isn't a CUSIP or ISIN")
+	}
+}
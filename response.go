@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Fields of NASDAQHistoricalAPIResponse and of its Data, the others are kept
+// in their Extra fields.
+var (
+	nasdaqResponseFields = []string{"Data", "ETag", "LastModified", "FetchedAt"}
+	nasdaqDataFields     = []string{"Symbol", "totalRecords", "tradesTable"}
+)
+
+// UnmarshalJSON parses a response like encoding/json would, keeping any fields
+// it doesn't model in Extra and Data.Extra so that they aren't lost when the
+// response is cached.
+func (ndr *NASDAQHistoricalAPIResponse) UnmarshalJSON(b []byte) error {
+	type plain NASDAQHistoricalAPIResponse
+	err := json.Unmarshal(b, (*plain)(ndr))
+	if err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(b, &fields)
+	if err != nil {
+		return err
+	}
+	ndr.Extra = unknownFields(fields, nasdaqResponseFields)
+
+	for k, v := range fields {
+		if strings.EqualFold(k, "Data") {
+			var dataFields map[string]json.RawMessage
+			if json.Unmarshal(v, &dataFields) == nil {
+				ndr.Data.Extra = unknownFields(dataFields, nasdaqDataFields)
+			}
+		}
+	}
+
+	return nil
+}
+
+// MarshalJSON adds the fields kept in Extra and Data.Extra back, if any.
+func (ndr NASDAQHistoricalAPIResponse) MarshalJSON() ([]byte, error) {
+	type plain NASDAQHistoricalAPIResponse
+	b, err := json.Marshal(plain(ndr))
+	if err != nil || (len(ndr.Extra) == 0 && len(ndr.Data.Extra) == 0) {
+		return b, err
+	}
+
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(b, &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ndr.Data.Extra) > 0 {
+		var dataFields map[string]json.RawMessage
+		err = json.Unmarshal(fields["Data"], &dataFields)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range ndr.Data.Extra {
+			dataFields[k] = v
+		}
+		fields["Data"], err = json.Marshal(dataFields)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for k, v := range ndr.Extra {
+		fields[k] = v
+	}
+
+	return json.Marshal(fields)
+}
+
+// unknownFields returns the fields not matching any of known, matched case
+// insensitively like encoding/json does, or nil if there are none.
+func unknownFields(fields map[string]json.RawMessage, known []string) map[string]json.RawMessage {
+	var unknown map[string]json.RawMessage
+
+	for k, v := range fields {
+		isKnown := false
+		for _, name := range known {
+			isKnown = isKnown || strings.EqualFold(k, name)
+		}
+		if !isKnown {
+			if unknown == nil {
+				unknown = make(map[string]json.RawMessage)
+			}
+			unknown[k] = v
+		}
+	}
+
+	return unknown
+}
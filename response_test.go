@@ -0,0 +1,159 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+// rawNASDAQResponse is an API response with fields that aren't modeled, at
+// the top, in its data and in its rows.
+const rawNASDAQResponse = `{
+  "data": {
+    "symbol": "X",
+    "totalRecords": 1,
+    "tradesTable": {"rows": [{"date": "01/02/2020", "close": "$10.00", "volume": "1,000", "open": "$10.00", "high": "$10.00", "low": "$10.00", "changePercent": "0.5%"}]},
+    "asOf": "Jan 2, 2020"
+  },
+  "message": null,
+  "status": {"rCode": 200}
+}`
+
+func serveRawNASDAQ(t *testing.T) {
+	serveNASDAQ(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-encoding", "gzip")
+		gw := gzip.NewWriter(w)
+		gw.Write([]byte(rawNASDAQResponse))
+		gw.Close()
+	})
+}
+
+func TestPersistRawKeepsTheResponseAsReceived(t *testing.T) {
+	serveRawNASDAQ(t)
+	fc := &FileCache{Dir: ".", PersistRaw: true}
+
+	_, err := NASDAQSource{Cache: fc}.HistoricalData("X", "2020-01-01", "2020-01-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := historicalCacheKey("X", "2020-01-01", "2020-01-31")
+	raw, err := os.ReadFile(fc.rawFile(key, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(raw) != rawNASDAQResponse {
+		t.Errorf("sidecar file has:\n%s\nwant the response as received", raw)
+	}
+	cached, err := os.ReadFile(fc.file(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(cached), "changePercent") {
+		t.Error("the row fields not modeled are in the cache file too")
+	}
+}
+
+func TestUnknownFieldsSurviveTheCache(t *testing.T) {
+	serveRawNASDAQ(t)
+	fc := &FileCache{Dir: "."}
+
+	_, err := NASDAQSource{Cache: fc}.HistoricalData("X", "2020-01-01", "2020-01-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(fc.rawFile(historicalCacheKey("X", "2020-01-01", "2020-01-31"), 0)); !os.IsNotExist(err) {
+		t.Error("sidecar file written without PersistRaw")
+	}
+
+	cached, ok, err := fc.Get(historicalCacheKey("X", "2020-01-01", "2020-01-31"))
+	if err != nil || !ok {
+		t.Fatalf("%v, %v", ok, err)
+	}
+	b, err := json.Marshal(cached)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fields struct {
+		Data struct {
+			AsOf string `json:"asOf"`
+		}
+		Status struct {
+			RCode int `json:"rCode"`
+		} `json:"status"`
+	}
+	err = json.Unmarshal(b, &fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields.Data.AsOf != "Jan 2, 2020" || fields.Status.RCode != 200 {
+		t.Errorf("after the cache round trip: %s", b)
+	}
+	if len(cached.Data.TradesTable.Rows) != 1 || cached.Data.Symbol != "X" {
+		t.Errorf("%s with %d rows after the cache round trip", cached.Data.Symbol, len(cached.Data.TradesTable.Rows))
+	}
+}
+
+func TestPersistRawKeepsEveryPage(t *testing.T) {
+	all := weekdayRows("2020-01-01", "2020-01-31", flatPrice(10))
+	// Capped at the newest 10 rows in the range asked for
+	serveNASDAQ(t, func(w http.ResponseWriter, r *http.Request) {
+		from, to := ISODateToTime(r.URL.Query().Get("fromdate")), ISODateToTime(r.URL.Query().Get("todate"))
+		var rows []*TradingData
+		for _, row := range all {
+			if d := NASDAQDateToTime(row.Date); !d.Before(from) && !d.After(to) {
+				rows = append(rows, row)
+			}
+		}
+		ndr := &NASDAQHistoricalAPIResponse{}
+		ndr.Data.Symbol = "X"
+		ndr.Data.TotalRecords = int64(len(rows))
+		if len(rows) > 10 {
+			rows = rows[:10]
+		}
+		ndr.Data.TradesTable.Rows = rows
+		w.Header().Set("content-encoding", "gzip")
+		gw := gzip.NewWriter(w)
+		json.NewEncoder(gw).Encode(ndr)
+		gw.Close()
+	})
+	fc := &FileCache{Dir: ".", PersistRaw: true}
+	key := historicalCacheKey("X", "2020-01-01", "2020-01-31")
+	// Left over from an earlier fetch in more requests
+	writeFile(t, fc.rawFile(key, 3), "{}")
+
+	ndr, err := NASDAQSource{Cache: fc}.HistoricalData("X", "2020-01-01", "2020-01-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The 23 rows in pages of 10, 10 and 3, newest first
+	var dates []string
+	for page := 0; page < 3; page++ {
+		data, err := os.ReadFile(fc.rawFile(key, page))
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, err := USMarket{}.ParseHistorical(data)
+		if err != nil {
+			t.Fatalf("page %d: %v", page+1, err)
+		}
+		for _, r := range parsed.Data.TradesTable.Rows {
+			dates = append(dates, r.Date)
+		}
+	}
+	var want []string
+	for _, r := range ndr.Data.TradesTable.Rows {
+		want = append(want, r.Date)
+	}
+	if len(want) != 23 || strings.Join(dates, " ") != strings.Join(want, " ") {
+		t.Errorf("pages kept with the rows %v, want the %d cached %v", dates, len(want), want)
+	}
+	if _, err := os.Stat(fc.rawFile(key, 3)); !os.IsNotExist(err) {
+		t.Error("a page left over from an earlier fetch is kept")
+	}
+}
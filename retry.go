@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// Number of times a rate limited or failed API request is retried.
+	nasdaqRetries = 3
+	// Delay before the first retry, doubled for every retry after it. A
+	// Retry-After header on the response takes precedence.
+	nasdaqRetryBackoff = time.Second
+	// Longest wait before a retry, whatever the backoff or the Retry-After
+	// header, so that a server asking for a day doesn't hang the program.
+	nasdaqMaxRetryWait = time.Minute
+	// sleep waits between retries until ctx is done, replaceable to skip the
+	// waits.
+	sleep = sleepContext
+)
+
+// doWithRetries sends r, retrying it when NASDAQ responds 429 Too Many
+// Requests or with a server error.
+func doWithRetries(c *http.Client, r *http.Request) (*http.Response, error) {
+	backoff := nasdaqRetryBackoff
+
+	for attempt := 0; ; attempt++ {
+		res, err := c.Do(r)
+		if err != nil {
+			return nil, err
+		}
+		if attempt == nasdaqRetries || !retryable(res.StatusCode) {
+			return res, nil
+		}
+		res.Body.Close()
+
+		wait, ok := retryAfter(res.Header.Get("retry-after"), time.Now())
+		if !ok {
+			wait = backoff
+		}
+		if wait > nasdaqMaxRetryWait {
+			wait = nasdaqMaxRetryWait
+		}
+		backoff *= 2
+
+		log.Printf("warning: %s for %s, retrying in %s", res.Status, r.URL, wait)
+		sleep(r.Context(), wait)
+		if err := r.Context().Err(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// sleepContext waits for d, or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
+
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// retryAfter parses a Retry-After header, given either as a number of seconds
+// or as an HTTP date, into how long to wait from now.
+func retryAfter(header string, now time.Time) (time.Duration, bool) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	t, err := http.ParseTime(header)
+	if err != nil {
+		return 0, false
+	}
+	if t.Before(now) {
+		return 0, true
+	}
+	return t.Sub(now), true
+}
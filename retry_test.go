@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryAfterRateLimit(t *testing.T) {
+	requests := 0
+	serveNASDAQ(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("retry-after", "7")
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+		writeNASDAQResponse(w, "X", weekdayRows("2020-01-01", "2020-01-31", flatPrice(10)))
+	})
+	nasdaqRetries = 3
+
+	defer func(s func(context.Context, time.Duration)) { sleep = s }(sleep)
+	var waits []time.Duration
+	sleep = func(ctx context.Context, d time.Duration) { waits = append(waits, d) }
+
+	ndr := CallNASDAQHistoricialAPI("X", "2020-01-01", "2020-01-31")
+
+	if requests != 2 || len(ndr.Data.TradesTable.Rows) == 0 {
+		t.Errorf("%d requests returning %d rows, want the second to succeed", requests, len(ndr.Data.TradesTable.Rows))
+	}
+	if len(waits) != 1 || waits[0] != 7*time.Second {
+		t.Errorf("waited %v, want 7s as the Retry-After header asked", waits)
+	}
+}
+
+func TestRetryAfterHeader(t *testing.T) {
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		header string
+		wait   time.Duration
+		ok     bool
+	}{
+		{"120", 2 * time.Minute, true},
+		{"Wed, 01 Jan 2020 12:00:30 GMT", 30 * time.Second, true},
+		{"Wed, 01 Jan 2020 11:00:00 GMT", 0, true},
+		{"", 0, false},
+		{"-1", 0, false},
+		{"soon", 0, false},
+	} {
+		wait, ok := retryAfter(tc.header, now)
+		if wait != tc.wait || ok != tc.ok {
+			t.Errorf("%q: waiting %v, %v, want %v, %v", tc.header, wait, ok, tc.wait, tc.ok)
+		}
+	}
+}
+
+func TestRetryWaitsAreCapped(t *testing.T) {
+	requests := 0
+	serveNASDAQ(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("retry-after", "86400")
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+		writeNASDAQResponse(w, "X", weekdayRows("2020-01-01", "2020-01-31", flatPrice(10)))
+	})
+	nasdaqRetries = 3
+
+	defer func(s func(context.Context, time.Duration)) { sleep = s }(sleep)
+	var waits []time.Duration
+	sleep = func(ctx context.Context, d time.Duration) { waits = append(waits, d) }
+
+	CallNASDAQHistoricialAPI("X", "2020-01-01", "2020-01-31")
+
+	if len(waits) != 1 || waits[0] != nasdaqMaxRetryWait {
+		t.Errorf("waited %v for a Retry-After of a day, want the %s maximum", waits, nasdaqMaxRetryWait)
+	}
+}
+
+func TestRetryWaitEndsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	start := time.Now()
+	sleepContext(ctx, time.Hour)
+	if waited := time.Since(start); waited > time.Second {
+		t.Errorf("waited %s after the cancellation, want it cut short", waited)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// PNLBasis is how the headline PNL of a DCA is computed.
+type PNLBasis int
+
+const (
+	// The total return over the total invested, the default.
+	SimplePNL PNLBasis = iota
+	// The internal rate of return of the contributions, compounded over the
+	// period, so that money invested late counts for less.
+	MoneyWeightedPNL
+	// The returns between contributions linked together, leaving out how
+	// the contributions were timed.
+	TimeWeightedPNL
+)
+
+// Basis of the PNL of DCAs and portfolios, see PNLBasis.
+var pnlBasis = SimplePNL
+
+func ParsePNLBasis(s string) (PNLBasis, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "simple":
+		return SimplePNL, nil
+	case "money-weighted", "mwr", "irr":
+		return MoneyWeightedPNL, nil
+	case "time-weighted", "twr":
+		return TimeWeightedPNL, nil
+	}
+	return SimplePNL, fmt.Errorf("unknown PNL basis '%s', expected simple, money-weighted or time-weighted", s)
+}
+
+func (b PNLBasis) String() string {
+	switch b {
+	case MoneyWeightedPNL:
+		return "money-weighted"
+	case TimeWeightedPNL:
+		return "time-weighted"
+	}
+	return "simple"
+}
+
+// pnlLabel is the PNL line's label, padded like the other labels.
+func pnlLabel() string {
+	switch pnlBasis {
+	case MoneyWeightedPNL:
+		return "PNL (MWR)      "
+	case TimeWeightedPNL:
+		return "PNL (TWR)      "
+	}
+	return "PNL            "
+}
+
+// PNL returns the return in percent of invested growing into total with
+// pnlBasis, the other bases computed from series.
+func PNL(invested, total float64, series []ValuePoint) float64 {
+	switch pnlBasis {
+	case MoneyWeightedPNL:
+		if r, ok := MoneyWeightedReturn(series); ok {
+			return r
+		}
+	case TimeWeightedPNL:
+		if len(series) > 1 {
+			return TimeWeightedReturn(series)
+		}
+	}
+	return SimplePNLOf(invested, total)
+}
+
+// SimplePNLOf returns the total return in percent of invested growing into
+// total, or 0 if nothing was invested.
+func SimplePNLOf(invested, total float64) float64 {
+	if invested == 0 {
+		return 0
+	}
+	return ((total / invested) - 1) * 100
+}
+
+// TimeWeightedReturn returns the return in percent of series with the money
+// invested taken out of each period's return, like AnnualizedVolatility.
+func TimeWeightedReturn(series []ValuePoint) float64 {
+	index := 1.0
+	for i := 1; i < len(series); i++ {
+		prev, vp := series[i-1], series[i]
+		if prev.Value <= 0 {
+			continue
+		}
+		contributed := vp.Invested - prev.Invested
+		index *= (vp.Value - contributed) / prev.Value
+	}
+	return (index - 1) * 100
+}
+
+// MoneyWeightedReturn returns the internal rate of return of the money
+// invested in series against its last value, compounded over the period of
+// series into a return in percent. Returns false if there's no period.
+func MoneyWeightedReturn(series []ValuePoint) (float64, bool) {
+	rate, years, ok := moneyWeightedRate(series)
+	if !ok {
+		return 0, false
+	}
+	return (math.Pow(1+rate, years) - 1) * 100, true
+}
+
+// moneyWeightedRate returns the annual internal rate of return of the money
+// invested in series against its last value, as a fraction, and the years
+// series covers. Returns false if there's no period or nothing invested.
+func moneyWeightedRate(series []ValuePoint) (rate, years float64, ok bool) {
+	if len(series) < 2 || series[len(series)-1].Invested <= 0 {
+		return 0, 0, false
+	}
+	start, end := series[0].Date, series[len(series)-1].Date
+	years = end.Sub(start).Hours() / 24 / 365.25
+	if years <= 0 {
+		return 0, 0, false
+	}
+
+	// What the contributions and the end value are worth at the end when
+	// growing at rate a year, falling as the rate rises
+	surplus := func(rate float64) float64 {
+		v := series[len(series)-1].Value
+		var invested float64
+		for _, vp := range series {
+			contributed := vp.Invested - invested
+			invested = vp.Invested
+			v -= contributed * math.Pow(1+rate, end.Sub(vp.Date).Hours()/24/365.25)
+		}
+		return v
+	}
+
+	lo, hi := -0.9999, 1.0
+	for surplus(hi) > 0 && hi < 1e6 {
+		hi *= 2
+	}
+	for i := 0; i < 200; i++ {
+		mid := (lo + hi) / 2
+		if surplus(mid) > 0 {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+
+	return (lo + hi) / 2, years, true
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPNLBases(t *testing.T) {
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	// 100 doubles, another 100 goes in at the top and it all halves
+	series := []ValuePoint{
+		{Date: day("2020-01-01"), Invested: 100, Value: 100},
+		{Date: day("2020-07-01"), Invested: 200, Value: 300},
+		{Date: day("2021-01-01"), Invested: 200, Value: 150},
+	}
+	defer func(b PNLBasis) { pnlBasis = b }(pnlBasis)
+
+	pnls := make(map[PNLBasis]float64)
+	for _, b := range []PNLBasis{SimplePNL, MoneyWeightedPNL, TimeWeightedPNL} {
+		pnlBasis = b
+		pnls[b] = PNL(200, 150, series)
+	}
+
+	if math.Abs(pnls[SimplePNL]-(-25)) > 1e-9 {
+		t.Errorf("simple PNL %.4f, want -25", pnls[SimplePNL])
+	}
+	// Doubling and then halving is no return at all, however it was funded
+	if math.Abs(pnls[TimeWeightedPNL]) > 1e-9 {
+		t.Errorf("time-weighted PNL %.4f, want 0", pnls[TimeWeightedPNL])
+	}
+	// The money invested late lost half, and weighs more than the early
+	// money that broke even
+	if mwr := pnls[MoneyWeightedPNL]; mwr >= pnls[TimeWeightedPNL] || mwr <= -50 {
+		t.Errorf("money-weighted PNL %.4f, want between -50 and the time-weighted 0", mwr)
+	}
+	if pnls[MoneyWeightedPNL] == pnls[SimplePNL] {
+		t.Errorf("money-weighted PNL is the simple PNL %.4f", pnls[SimplePNL])
+	}
+}
+
+func TestParsePNLBasis(t *testing.T) {
+	for s, want := range map[string]PNLBasis{"simple": SimplePNL, "IRR": MoneyWeightedPNL, " time-weighted ": TimeWeightedPNL} {
+		b, err := ParsePNLBasis(s)
+		if err != nil || b != want {
+			t.Errorf("ParsePNLBasis(%q) = %v, %v, want %v", s, b, err, want)
+		}
+	}
+	if _, err := ParsePNLBasis("geometric"); err == nil {
+		t.Error("no error for an unknown basis")
+	}
+}
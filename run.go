@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// RunOptions is a complete DCA backtest: what to invest in and when, and the
+// Options tweaking how it's simulated.
+type RunOptions struct {
+	Symbols   []SymbolSpec
+	From      string // YYYY-MM-DD
+	To        string // YYYY-MM-DD, defaults to today
+	Frequency Frequency
+	Amount    float64
+	Options
+}
+
+// Run backtests a portfolio, returning an error rather than panicking on bad
+// options or data, e.g. for embedding the backtest in other programs.
+func Run(ro RunOptions) (dp *DCAPortfolio, err error) {
+	if len(ro.Symbols) == 0 {
+		return nil, errors.New("no symbols to DCA into")
+	}
+	if ro.To == "" {
+		ro.To = Today()
+	}
+	err = ValidateDateRange(ro.From, ro.To)
+	if err != nil {
+		return nil, err
+	}
+	if ro.Frequency == 0 {
+		ro.Frequency = Monthly
+	}
+	if ro.Amount <= 0 {
+		return nil, fmt.Errorf("amount must be positive, got %g", ro.Amount)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			dp = nil
+			if e, ok := r.(error); ok {
+				err = e
+			} else {
+				err = fmt.Errorf("%v", r)
+			}
+		}
+	}()
+
+	return NewDCAPortfolio(ro.Symbols, ro.From, ro.To, ro.Frequency, ro.Amount, ro.Options), nil
+}
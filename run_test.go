@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	source := testSource{"X": weekdayRows("2020-01-01", "2020-12-31", flatPrice(25))}
+
+	dp, err := Run(RunOptions{
+		Symbols: []SymbolSpec{{Symbol: "X"}},
+		From:    "2020-01-01",
+		To:      "2020-12-31",
+		Amount:  100,
+		Options: Options{Source: source},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Monthly by default
+	if len(dp.Positions) != 1 || dp.TotalInvested != 1200 || !approx(dp.Positions[0].Units, 48) {
+		t.Errorf("%d positions investing %v, want 1200 in X", len(dp.Positions), dp.TotalInvested)
+	}
+	if !approx(dp.TotalReturn, 1200) {
+		t.Errorf("return %v at a flat price, want 1200", dp.TotalReturn)
+	}
+}
+
+func TestRunReturnsErrors(t *testing.T) {
+	source := testSource{"X": weekdayRows("2020-01-01", "2020-12-31", flatPrice(25))}
+	valid := RunOptions{Symbols: []SymbolSpec{{Symbol: "X"}}, From: "2020-01-01", To: "2020-12-31", Amount: 100, Options: Options{Source: source}}
+
+	for name, modify := range map[string]func(*RunOptions){
+		"no symbols":     func(ro *RunOptions) { ro.Symbols = nil },
+		"invalid date":   func(ro *RunOptions) { ro.From = "2020-13-01" },
+		"no amount":      func(ro *RunOptions) { ro.Amount = 0 },
+		"unknown symbol": func(ro *RunOptions) { ro.Symbols = []SymbolSpec{{Symbol: "NOPE"}} },
+	} {
+		ro := valid
+		modify(&ro)
+		dp, err := Run(ro)
+		if err == nil || dp != nil {
+			t.Errorf("%s: %v, %v, want an error", name, dp, err)
+		}
+	}
+
+	_, err := Run(RunOptions{Symbols: []SymbolSpec{{Symbol: "X"}}, From: "2020-01-01", To: "2020-12-31", Amount: 100, Options: Options{Source: panickingSource{ErrNoTradingData}}})
+	if !errors.Is(err, ErrNoTradingData) {
+		t.Errorf("error %v, want the data source's", err)
+	}
+}
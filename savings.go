@@ -0,0 +1,52 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// FixedRateComparison is what the portfolio's contributions would have grown
+// into at a fixed annual rate instead, in a savings account or bonds.
+type FixedRateComparison struct {
+	Rate        float64 // In percent a year
+	TotalReturn float64
+	PNL         float64
+}
+
+// CompareToSavings compounds the portfolio's contributions at apy, the
+// effective annual yield in percent of a savings account, until the end date.
+func (dp *DCAPortfolio) CompareToSavings(apy float64) *FixedRateComparison {
+	return dp.compareAtRate(apy, apy/100)
+}
+
+// CompareToBonds invests the portfolio's contributions into bonds yielding
+// yield percent a year, paid as semiannual coupons reinvested at the same
+// yield, until the end date.
+func (dp *DCAPortfolio) CompareToBonds(yield float64) *FixedRateComparison {
+	return dp.compareAtRate(yield, math.Pow(1+yield/100/2, 2)-1)
+}
+
+// compareAtRate grows the contributions at the effective annual rate, for
+// rate in percent as quoted.
+func (dp *DCAPortfolio) compareAtRate(rate, effective float64) *FixedRateComparison {
+	fc := &FixedRateComparison{Rate: rate}
+
+	for _, cf := range dp.CashFlows() {
+		if cf.Amount < 0 {
+			fc.TotalReturn += -cf.Amount * compound(effective, cf.Date, dp.To)
+		}
+	}
+	fc.PNL = SimplePNLOf(dp.TotalInvested, fc.TotalReturn)
+
+	return fc
+}
+
+// compound returns how much money grows between from and to at the effective
+// annual rate.
+func compound(rate float64, from, to time.Time) float64 {
+	years := to.Sub(from).Hours() / 24 / 365.25
+	if years <= 0 {
+		return 1
+	}
+	return math.Pow(1+rate, years)
+}
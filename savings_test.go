@@ -0,0 +1,60 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// fixedRatePortfolio invests 1,000 at the start of 2020 and another 1,000 a
+// year later, valued four years after the start.
+func fixedRatePortfolio() *DCAPortfolio {
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	d := &DCA{Transactions: []Transaction{
+		{Date: day("2020-01-01"), Amount: 1000, Units: 10},
+		{Date: day("2021-01-01"), Amount: 1000, Units: 10},
+	}}
+	return &DCAPortfolio{Positions: []*DCA{d}, TotalInvested: 2000, From: day("2020-01-01"), To: day("2024-01-01")}
+}
+
+func TestSavingsCompoundInterest(t *testing.T) {
+	fc := fixedRatePortfolio().CompareToSavings(5)
+
+	// 4 years of 5% on the first 1,000, and the 1,095 days after the leap
+	// year on the second
+	want := 1000*math.Pow(1.05, 4) + 1000*math.Pow(1.05, 1095/365.25)
+	if math.Abs(fc.TotalReturn-want) > 1e-6 || fc.Rate != 5 {
+		t.Errorf("savings at %v%% worth %v, want %v", fc.Rate, fc.TotalReturn, want)
+	}
+	if math.Abs(fc.PNL-(want/2000-1)*100) > 1e-6 {
+		t.Errorf("savings PNL %v, want %v", fc.PNL, (want/2000-1)*100)
+	}
+}
+
+func TestBondsGrowAtTheYield(t *testing.T) {
+	savings := fixedRatePortfolio().CompareToSavings(4)
+	bonds := fixedRatePortfolio().CompareToBonds(4)
+
+	// Semiannual coupons of 2% reinvested, 8 of them on the first 1,000
+	want := 1000*math.Pow(1.02, 8) + 1000*math.Pow(1.02, 2*1095/365.25)
+	if math.Abs(bonds.TotalReturn-want) > 1e-6 {
+		t.Errorf("bonds at 4%% worth %v, want %v", bonds.TotalReturn, want)
+	}
+	// Compounding the coupons beats a savings account quoting the same rate
+	if bonds.TotalReturn <= savings.TotalReturn {
+		t.Errorf("bonds worth %v, savings %v at the same rate", bonds.TotalReturn, savings.TotalReturn)
+	}
+}
+
+func TestFixedRateComparisonWithNothingInvested(t *testing.T) {
+	dp := &DCAPortfolio{From: ISODateToTime("2020-01-01"), To: ISODateToTime("2020-12-31")}
+
+	for _, fc := range []*FixedRateComparison{dp.CompareToSavings(5), dp.CompareToBonds(4)} {
+		if fc.TotalReturn != 0 || fc.PNL != 0 {
+			t.Errorf("%v%%: worth %v with a PNL of %v, want 0 with nothing invested", fc.Rate, fc.TotalReturn, fc.PNL)
+		}
+	}
+}
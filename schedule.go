@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is the days of the month purchases are made on, e.g. 1 and 15 for
+// semi-monthly paydays. Days past the end of a shorter month fall on its last
+// day.
+type Schedule []int
+
+// ParseSchedule parses days of the month separated by commas, e.g. "1,15".
+func ParseSchedule(s string) (Schedule, error) {
+	var sch Schedule
+	seen := make(map[int]bool)
+
+	for _, field := range strings.Split(s, ",") {
+		day, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || day < 1 || day > 31 {
+			return nil, fmt.Errorf("invalid day of the month '%s' in schedule '%s'", field, s)
+		}
+		if !seen[day] {
+			seen[day] = true
+			sch = append(sch, day)
+		}
+	}
+	sort.Ints(sch)
+
+	return sch, nil
+}
+
+// PerYear returns the number of purchases per year.
+func (sch Schedule) PerYear() float64 {
+	return float64(12 * len(sch))
+}
+
+// Next returns the first scheduled date after t.
+func (sch Schedule) Next(t time.Time) time.Time {
+	for months := 0; ; months++ {
+		for _, day := range sch {
+			next := addMonths(t, months, day)
+			if next.After(t) {
+				return next
+			}
+		}
+	}
+}
+
+// Dates returns the scheduled dates from from up until to.
+func (sch Schedule) Dates(from, to time.Time) []time.Time {
+	var dates []time.Time
+	for t := sch.Next(from.AddDate(0, 0, -1)); t.Before(to); t = sch.Next(t) {
+		dates = append(dates, t)
+	}
+	return dates
+}
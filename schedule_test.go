@@ -0,0 +1,51 @@
+package main
+
+import "testing"
+
+func TestFirstAndFifteenthSchedule(t *testing.T) {
+	sch, err := ParseSchedule("15, 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sch.PerYear() != 24 {
+		t.Errorf("%v purchases a year, want 24", sch.PerYear())
+	}
+
+	// February 1 and 15 2020 are on a Saturday, March 1 on a Sunday
+	source := testSource{"X": weekdayRows("2020-01-01", "2020-03-31", flatPrice(10))}
+	d := NewDCA("X", "2020-01-01", "2020-03-31", Monthly, 100, Options{Source: source, Schedule: sch})
+
+	want := []string{"2020-01-01", "2020-01-15", "2020-02-03", "2020-02-17", "2020-03-02", "2020-03-16"}
+	if len(d.Transactions) != len(want) {
+		t.Fatalf("%d purchases, want two a month", len(d.Transactions))
+	}
+	for i, tx := range d.Transactions {
+		if got := tx.Date.Format("2006-01-02"); got != want[i] {
+			t.Errorf("purchase %d on %s, want %s", i+1, got, want[i])
+		}
+	}
+}
+
+func TestScheduleClampsToTheEndOfTheMonth(t *testing.T) {
+	sch, err := ParseSchedule("15,31")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dates := sch.Dates(ISODateToTime("2020-02-01"), ISODateToTime("2020-05-01"))
+	want := []string{"2020-02-15", "2020-02-29", "2020-03-15", "2020-03-31", "2020-04-15", "2020-04-30"}
+	if len(dates) != len(want) {
+		t.Fatalf("dates %v, want %v", dates, want)
+	}
+	for i, date := range dates {
+		if got := date.Format("2006-01-02"); got != want[i] {
+			t.Errorf("date %d is %s, want %s", i+1, got, want[i])
+		}
+	}
+
+	for _, s := range []string{"0", "32", "1,x", ""} {
+		if _, err := ParseSchedule(s); err == nil {
+			t.Errorf("no error for schedule %q", s)
+		}
+	}
+}
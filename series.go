@@ -0,0 +1,207 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Transaction is a purchase, or a sale when Amount and Units are negative.
+type Transaction struct {
+	Date   time.Time
+	Price  float64
+	Amount float64
+	Units  float64
+	// Rebalance is set for trades moving money between positions rather than
+	// investing new money.
+	Rebalance bool `json:",omitempty"`
+	// Dividend is set for purchases reinvesting dividends.
+	Dividend bool `json:",omitempty"`
+	// Fee charged on the trade, already taken out of its Units.
+	Fee float64 `json:",omitempty"`
+}
+
+// ValuePoint is the money invested so far and what it's worth on a date.
+type ValuePoint struct {
+	Date     time.Time
+	Invested float64
+	Value    float64
+}
+
+// ValueSeries returns the value of the position on each transaction date and
+// on the end date.
+func (d *DCA) ValueSeries() []ValuePoint {
+	return d.valueSeries(d.seriesDates())
+}
+
+func (d *DCA) seriesDates() []time.Time {
+	var dates []time.Time
+	for _, t := range d.Transactions {
+		if len(dates) == 0 || !dates[len(dates)-1].Equal(t.Date) {
+			dates = append(dates, t.Date)
+		}
+	}
+	if len(dates) == 0 || dates[len(dates)-1].Before(d.To) {
+		dates = append(dates, d.To)
+	}
+	return dates
+}
+
+// valueSeries values the position on the given dates, which must be in
+// ascending order.
+func (d *DCA) valueSeries(dates []time.Time) []ValuePoint {
+	var series []ValuePoint
+	var invested, units float64
+	next := 0
+
+	for _, date := range dates {
+		for ; next < len(d.Transactions) && !d.Transactions[next].Date.After(date); next++ {
+			t := d.Transactions[next]
+			units += t.Units
+			if !t.Rebalance && !t.Dividend {
+				invested += t.Amount
+			}
+		}
+
+		vp := ValuePoint{Date: date, Invested: invested}
+		if !date.Before(d.To) {
+			vp.Value = d.TotalReturn
+		} else if units != 0 && d.prices != nil {
+			vp.Value = units * d.prices.PriceCloseToDate(date)
+		}
+
+		series = append(series, vp)
+	}
+
+	return series
+}
+
+// DailyValueSeries returns the value of the position on each trading day
+// from the start, and on the end date.
+func (d *DCA) DailyValueSeries() []ValuePoint {
+	return d.valueSeries(d.tradingDates())
+}
+
+func (d *DCA) tradingDates() []time.Time {
+	var dates []time.Time
+	if d.prices != nil {
+		for _, r := range RowsAscending(RowsBetween(d.prices.Data.TradesTable.Rows, d.From, d.To)) {
+			dates = append(dates, NASDAQDateToTime(r.Date))
+		}
+	}
+	if len(dates) == 0 || dates[len(dates)-1].Before(d.To) {
+		dates = append(dates, d.To)
+	}
+	return dates
+}
+
+// ValueSeries returns the value of the portfolio on each date any of its
+// positions has a transaction on, and on the end date.
+func (dp *DCAPortfolio) ValueSeries() []ValuePoint {
+	return dp.valueSeries((*DCA).seriesDates)
+}
+
+// DailyValueSeries returns the value of the portfolio on each trading day of
+// any of its positions, and on the end date.
+func (dp *DCAPortfolio) DailyValueSeries() []ValuePoint {
+	return dp.valueSeries((*DCA).tradingDates)
+}
+
+func (dp *DCAPortfolio) valueSeries(datesOf func(*DCA) []time.Time) []ValuePoint {
+	seen := make(map[time.Time]bool)
+	var dates []time.Time
+
+	for _, d := range dp.Positions {
+		for _, date := range datesOf(d) {
+			if !seen[date] {
+				seen[date] = true
+				dates = append(dates, date)
+			}
+		}
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+
+	return dp.valuesOn(dates)
+}
+
+// valuesOn values the portfolio on the given dates, which must be in
+// ascending order.
+func (dp *DCAPortfolio) valuesOn(dates []time.Time) []ValuePoint {
+	series := make([]ValuePoint, len(dates))
+	for i, date := range dates {
+		series[i].Date = date
+	}
+
+	for _, d := range dp.Positions {
+		for i, vp := range d.valueSeries(dates) {
+			series[i].Invested += vp.Invested
+			series[i].Value += vp.Value
+		}
+	}
+
+	return series
+}
+
+// AnnualizedVolatility returns the standard deviation of the returns between
+// the points of series in percent, scaled to a year by the square root of the
+// number of points per year. Money invested on a date is taken out of the
+// return up to it, so that only the price moves of what was already held
+// count. Returns 0 if there are too few points.
+func AnnualizedVolatility(series []ValuePoint) float64 {
+	var returns []float64
+	for i := 1; i < len(series); i++ {
+		prev, vp := series[i-1], series[i]
+		if prev.Value <= 0 {
+			continue
+		}
+		contributed := vp.Invested - prev.Invested
+		returns = append(returns, (vp.Value-contributed)/prev.Value-1)
+	}
+	if len(returns) < 2 {
+		return 0
+	}
+
+	years := series[len(series)-1].Date.Sub(series[0].Date).Hours() / 24 / 365.25
+	if years <= 0 {
+		return 0
+	}
+	perYear := float64(len(series)-1) / years
+
+	return StdDev(returns) * math.Sqrt(perYear) * 100
+}
+
+// CurrentDrawdown returns how far in percent the value of series has fallen
+// from its highest point by its last point. Like AnnualizedVolatility, money
+// invested is taken out so that contributions don't count as gains.
+func CurrentDrawdown(series []ValuePoint) float64 {
+	current, _ := drawdowns(series)
+	return current
+}
+
+// MaxDrawdown returns the largest fall in percent of the value of series from
+// a high to a later low, with the money invested taken out like
+// CurrentDrawdown.
+func MaxDrawdown(series []ValuePoint) float64 {
+	_, max := drawdowns(series)
+	return max
+}
+
+func drawdowns(series []ValuePoint) (current, max float64) {
+	index, peak := 1.0, 1.0
+	for i := 1; i < len(series); i++ {
+		prev, vp := series[i-1], series[i]
+		if prev.Value <= 0 {
+			continue
+		}
+		contributed := vp.Invested - prev.Invested
+		index *= (vp.Value - contributed) / prev.Value
+		if index > peak {
+			peak = index
+		}
+		if dd := (1 - index/peak) * 100; dd > max {
+			max = dd
+		}
+	}
+
+	return (1 - index/peak) * 100, max
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAnnualizedVolatilityOfKnownDispersion(t *testing.T) {
+	// Weekly returns of +1% and -1% in turn over a year
+	start := ISODateToTime("2020-01-01")
+	series := []ValuePoint{{Date: start, Invested: 100, Value: 100}}
+	for i := 1; i <= 52; i++ {
+		r := 0.01
+		if i%2 == 0 {
+			r = -0.01
+		}
+		prev := series[i-1]
+		series = append(series, ValuePoint{Date: start.AddDate(0, 0, 7*i), Invested: 100, Value: prev.Value * (1 + r)})
+	}
+
+	// Sample standard deviation of the returns, scaled by the square root of
+	// the 52 returns in 364 days
+	want := 0.01 * math.Sqrt(52.0/51) * math.Sqrt(52/(364/365.25)) * 100
+	if got := AnnualizedVolatility(series); math.Abs(got-want) > 1e-9 {
+		t.Errorf("volatility %.6f %%, want %.6f %%", got, want)
+	}
+}
+
+func TestAnnualizedVolatilityIgnoresContributions(t *testing.T) {
+	start := ISODateToTime("2020-01-01")
+	var series []ValuePoint
+	for i := 0; i < 12; i++ {
+		invested := 100 * float64(i+1)
+		series = append(series, ValuePoint{Date: start.AddDate(0, i, 0), Invested: invested, Value: invested})
+	}
+
+	if got := AnnualizedVolatility(series); got != 0 {
+		t.Errorf("volatility %v %% of contributions at a flat price, want 0", got)
+	}
+	if got := AnnualizedVolatility(series[:2]); got != 0 {
+		t.Errorf("volatility %v %% of a single return, want 0", got)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+var (
+	// apiContext is used for all API requests, cancelled on interrupt.
+	apiContext = context.Background()
+	// fileWrites is held while writing a cache file, so that an interrupt
+	// doesn't exit half way through the write.
+	fileWrites sync.Mutex
+)
+
+// handleInterrupts cancels the API requests in progress on SIGINT or SIGTERM,
+// waits for any cache file being written to be done and exits. It's the only
+// one exiting once interrupted, see exit.
+func handleInterrupts() {
+	ctx, cancel := context.WithCancel(context.Background())
+	apiContext = ctx
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		sig := <-sigs
+		fmt.Fprintf(os.Stderr, "\nReceived %s, exiting\n", sig)
+		cancel()
+
+		fileWrites.Lock()
+		os.Exit(ExitInterrupted)
+	}()
+}
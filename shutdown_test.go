@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCancelMidFetchLeavesNoCacheFile(t *testing.T) {
+	defer func(ctx context.Context) { apiContext = ctx }(apiContext)
+	ctx, cancel := context.WithCancel(context.Background())
+	apiContext = ctx
+
+	serveNASDAQ(t, func(w http.ResponseWriter, r *http.Request) {
+		// Interrupted half way through the response
+		w.Header().Set("content-encoding", "gzip")
+		w.Write([]byte{0x1f, 0x8b, 0x08, 0x00})
+		w.(http.Flusher).Flush()
+		cancel()
+		<-r.Context().Done()
+	})
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("no error fetching after the cancellation")
+			}
+		}()
+		NASDAQSource{Cache: &FileCache{Dir: "."}}.HistoricalData("X", "2020-01-01", "2020-01-31")
+	}()
+
+	entries, err := os.ReadDir(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		// Lock files are left in place
+		if !strings.HasSuffix(e.Name(), ".lock") {
+			t.Errorf("%s left behind by the cancelled fetch", e.Name())
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import "golang.org/x/sync/singleflight"
+
+// fetchGroup coalesces concurrent fetches for the same key into one, so that
+// e.g. the scenarios of a batch asking for the same data at once share a
+// single fetch.
+type fetchGroup struct {
+	g singleflight.Group
+}
+
+// fetchPanic carries a panic in a fetch to all the callers waiting for it.
+type fetchPanic struct {
+	value interface{}
+}
+
+// Do calls fn unless a call for key is already in flight, in which case it
+// waits for it and returns its result, which is shared and must not be
+// modified. A panic in fn is repeated as is in all the callers waiting for
+// it, so that its error still decides the exit code.
+func (fg *fetchGroup) Do(key string, fn func() (*NASDAQHistoricalAPIResponse, error)) (*NASDAQHistoricalAPIResponse, error) {
+	v, err, _ := fg.g.Do(key, func() (v interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				v = fetchPanic{r}
+			}
+		}()
+		return fn()
+	})
+	if p, ok := v.(fetchPanic); ok {
+		panic(p.value)
+	}
+	ndr, _ := v.(*NASDAQHistoricalAPIResponse)
+	return ndr, err
+}
+
+// Fetches from the NASDAQ API in flight, by cache key.
+var nasdaqFetches fetchGroup
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetchGroupCallsSlowSourceOnce(t *testing.T) {
+	var g fetchGroup
+	var calls int32
+	slow := func() (*NASDAQHistoricalAPIResponse, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(100 * time.Millisecond)
+		return testSource{"X": weekdayRows("2020-01-01", "2020-01-31", flatPrice(10))}.HistoricalData("X", "2020-01-01", "2020-01-31")
+	}
+
+	const n = 10
+	results := make([]*NASDAQHistoricalAPIResponse, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = g.Do("X-2020-01-01-2020-01-31", slow)
+		}(i)
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("source called %d times, want once", calls)
+	}
+	for i, ndr := range results {
+		if ndr != results[0] {
+			t.Errorf("caller %d got a result of its own", i)
+		}
+	}
+}
+
+func TestFetchGroupRepeatsPanics(t *testing.T) {
+	var g fetchGroup
+	apiErr := &APIError{URL: "https://api.nasdaq.com", Err: errors.New("timeout")}
+
+	defer func() {
+		if r := recover(); r != apiErr {
+			t.Errorf("panicked with %v, want the fetch's %v", r, apiErr)
+		}
+	}()
+	g.Do("X", func() (*NASDAQHistoricalAPIResponse, error) { panic(apiErr) })
+}
+
+func TestRunBatchSharesConcurrentFetches(t *testing.T) {
+	var requests int32
+	serveNASDAQ(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		time.Sleep(100 * time.Millisecond)
+		writeNASDAQResponse(w, "X", weekdayRows("2020-01-01", "2020-12-31", flatPrice(10)))
+	})
+
+	var scenarios []Scenario
+	for _, amount := range []float64{100, 200, 300, 400} {
+		scenarios = append(scenarios, Scenario{
+			Symbols:   []SymbolSpec{{Symbol: "X"}},
+			From:      "2020-01-01",
+			To:        "2020-12-31",
+			Amount:    amount,
+			Frequency: Monthly,
+		})
+	}
+	opts := Options{Source: NASDAQSource{Cache: NewLRUCache(8, &FileCache{Dir: "."})}}
+
+	var buf bytes.Buffer
+	err := RunBatch(scenarios, opts, len(scenarios), &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if requests != 1 {
+		t.Errorf("%d requests to the NASDAQ API, want one", requests)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(scenarios)+1 {
+		t.Fatalf("%d CSV lines, want a header and %d rows:\n%s", len(lines), len(scenarios), buf.String())
+	}
+	for i, amount := range []string{"100", "200", "300", "400"} {
+		if !strings.Contains(lines[i+1], ","+amount+",monthly,") {
+			t.Errorf("row %d is %s, want the scenario investing %s", i+1, lines[i+1], amount)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SymbolSpec is a symbol to DCA into, optionally overriding the portfolio's
+// purchase schedule. It's given as SYMBOL[:key=value...], e.g.
+// "AAPL:frequency=weekly:amount=100", with the keys:
+//
+//   - frequency: daily, weekly, biweekly, monthly, quarterly or yearly
+//   - amount: amount to invest on every purchase, instead of a share of the
+//     portfolio's amount
+//   - account: label of the account the symbol is held in, so that the same
+//     symbol can be held in several accounts, e.g. "AAPL:account=ira"
+//   - from, to: YYYY-MM-DD dates to DCA into the symbol between instead of
+//     the portfolio's, e.g. from its listing date
+//   - expense-ratio: annual expense ratio in percent of an ETF, e.g. 0.2,
+//     dragging on its value day by day
+type SymbolSpec struct {
+	Symbol    string
+	Frequency Frequency // 0 uses the portfolio's frequency
+	Amount    float64   // 0 uses a share of the portfolio's amount
+	Account   string
+	From      string // Empty uses the portfolio's dates
+	To        string
+	// 0 uses the portfolio's expense ratio
+	ExpenseRatio float64
+}
+
+func ParseSymbolSpec(spec string) (SymbolSpec, error) {
+	parts := strings.Split(spec, ":")
+
+	// Symbols are case-insensitive, normalize them so that caching and
+	// deduplication treat "aapl" and "AAPL" the same
+	ss := SymbolSpec{Symbol: strings.ToUpper(strings.TrimSpace(parts[0]))}
+	if ss.Symbol == "" {
+		return ss, fmt.Errorf("symbol spec '%s' has no symbol", spec)
+	}
+
+	for _, part := range parts[1:] {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return ss, fmt.Errorf("symbol spec '%s': expected key=value, got '%s'", spec, part)
+		}
+
+		var err error
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "frequency":
+			ss.Frequency, err = ParseFrequency(value)
+		case "amount":
+			ss.Amount, err = strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err == nil && ss.Amount <= 0 {
+				err = fmt.Errorf("amount must be positive")
+			}
+		case "account":
+			ss.Account = strings.TrimSpace(value)
+		case "from":
+			ss.From, err = parseSpecDate(value)
+		case "to":
+			ss.To, err = parseSpecDate(value)
+		case "expense-ratio":
+			ss.ExpenseRatio, err = strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err == nil && (ss.ExpenseRatio < 0 || ss.ExpenseRatio >= 100) {
+				err = fmt.Errorf("expense ratio must be between 0 and 100 percent")
+			}
+		default:
+			err = fmt.Errorf("unknown key '%s'", key)
+		}
+		if err != nil {
+			return ss, fmt.Errorf("symbol spec '%s': %w", spec, err)
+		}
+	}
+
+	if ss.From != "" && ss.To != "" {
+		err := ValidateDateRange(ss.From, ss.To)
+		if err != nil {
+			return ss, fmt.Errorf("symbol spec '%s': %w", spec, err)
+		}
+	}
+
+	return ss, nil
+}
+
+func parseSpecDate(value string) (string, error) {
+	t, err := time.Parse("2006-01-02", strings.TrimSpace(value))
+	if err != nil {
+		return "", fmt.Errorf("invalid date '%s', expected YYYY-MM-DD", value)
+	}
+	return t.Format("2006-01-02"), nil
+}
+
+func ParseSymbolSpecs(specs []string) ([]SymbolSpec, error) {
+	var parsed []SymbolSpec
+
+	for _, spec := range specs {
+		ss, err := ParseSymbolSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, ss)
+	}
+
+	return parsed, nil
+}
+
+// DedupeSymbolSpecs drops specs whose symbol, ignoring case, was already
+// given for the same account, logging a warning for each one dropped.
+func DedupeSymbolSpecs(specs []SymbolSpec) []SymbolSpec {
+	seen := make(map[string]bool)
+	var deduped []SymbolSpec
+
+	for _, ss := range specs {
+		key := strings.ToUpper(ss.Symbol) + ":" + ss.Account
+		if seen[key] {
+			log.Printf("warning: ignoring duplicate symbol %s", ss.Symbol)
+			continue
+		}
+		seen[key] = true
+		deduped = append(deduped, ss)
+	}
+
+	return deduped
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMixedCaseSymbolsAreNormalized(t *testing.T) {
+	requests := serveCountedNASDAQ(t)
+	specs, err := ParseSymbolSpecs([]string{"aapl", " AAPL", "Aapl"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := Options{Source: NASDAQSource{Cache: &FileCache{Dir: "."}}}
+	dp := NewDCAPortfolio(specs, "2020-01-01", "2020-01-31", Monthly, 100, opts)
+
+	if len(dp.Positions) != 1 || dp.Positions[0].Symbol != "AAPL" {
+		t.Errorf("positions %v, want only AAPL", dp.Symbols())
+	}
+	files, err := filepath.Glob("*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0] != CacheKey("AAPL", "2020-01-01", "2020-01-31")+".json" {
+		t.Errorf("cache files %v, want one for AAPL", files)
+	}
+	if requests() != 1 {
+		t.Errorf("%d requests, want 1", requests())
+	}
+}
+
+func TestSymbolDateRanges(t *testing.T) {
+	// Y listed in the middle of the year
+	source := testSource{
+		"X": weekdayRows("2020-01-01", "2020-12-31", flatPrice(10)),
+		"Y": weekdayRows("2020-07-01", "2020-12-31", flatPrice(10)),
+	}
+	specs, err := ParseSymbolSpecs([]string{"X:to=2020-09-30", "Y:from=2020-07-01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dp := NewDCAPortfolio(specs, "2020-01-01", "2020-12-31", Monthly, 200, Options{Source: source})
+
+	for i, want := range []struct {
+		from, to string
+		buys     int
+	}{{"2020-01-01", "2020-09-30", 9}, {"2020-07-01", "2020-12-31", 6}} {
+		d := dp.Positions[i]
+		if got := d.From.Format("2006-01-02") + " - " + d.To.Format("2006-01-02"); got != want.from+" - "+want.to {
+			t.Errorf("%s DCA:ed over %s, want %s - %s", d.Symbol, got, want.from, want.to)
+		}
+		if len(d.Transactions) != want.buys {
+			t.Errorf("%s bought %d times, want %d", d.Symbol, len(d.Transactions), want.buys)
+		}
+	}
+	if got := dp.From.Format("2006-01-02") + " - " + dp.To.Format("2006-01-02"); got != "2020-01-01 - 2020-12-31" {
+		t.Errorf("portfolio over %s, want all of 2020", got)
+	}
+	if dp.TotalInvested != 15*100 {
+		t.Errorf("invested %v, want 1500", dp.TotalInvested)
+	}
+
+	if _, err := ParseSymbolSpec("Y:from=2020-07-01:to=2020-06-01"); err == nil {
+		t.Error("no error for a symbol's inverted date range")
+	}
+}
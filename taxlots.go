@@ -0,0 +1,67 @@
+package main
+
+import "time"
+
+// Lots held longer than this are long-term when sold.
+const longTermHolding = 1 // Years
+
+// TaxLot is the units bought by a purchase not sold yet, and what they cost.
+type TaxLot struct {
+	Date  time.Time
+	Units float64
+	Cost  float64
+}
+
+// RealizedGains are the gains realized by sells, less their fees, split by
+// how long the units sold were held. Losses are negative.
+type RealizedGains struct {
+	ShortTerm float64
+	LongTerm  float64
+}
+
+// TaxLots replays the transactions, each buy its own lot costing its
+// amount including fees, with sells consuming the oldest lots first.
+// Returns the lots still held and the gains the sells realized.
+func (d *DCA) TaxLots() ([]TaxLot, RealizedGains) {
+	var lots []TaxLot
+	var gains RealizedGains
+
+	for _, t := range d.Transactions {
+		if t.Units > 0 {
+			cost := t.Amount
+			if t.Rebalance || t.Dividend {
+				// Their fees aren't part of their amount
+				cost += t.Fee
+			}
+			lots = append(lots, TaxLot{Date: t.Date, Units: t.Units, Cost: cost})
+			continue
+		}
+
+		sold := -t.Units
+		proceeds := -t.Amount - t.Fee
+		for sold > 1e-12 && len(lots) > 0 {
+			lot := &lots[0]
+			units := sold
+			if units > lot.Units {
+				units = lot.Units
+			}
+
+			share := units / lot.Units
+			gain := proceeds*units/-t.Units - lot.Cost*share
+			if t.Date.After(lot.Date.AddDate(longTermHolding, 0, 0)) {
+				gains.LongTerm += gain
+			} else {
+				gains.ShortTerm += gain
+			}
+
+			lot.Cost -= lot.Cost * share
+			lot.Units -= units
+			sold -= units
+			if lot.Units <= 1e-12 {
+				lots = lots[1:]
+			}
+		}
+	}
+
+	return lots, gains
+}
@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestFIFOLotsAndGainsByHoldingPeriod(t *testing.T) {
+	d := &DCA{Transactions: []Transaction{
+		{Date: ISODateToTime("2020-01-02"), Price: 10, Amount: 100, Units: 10},
+		{Date: ISODateToTime("2020-06-01"), Price: 20, Amount: 200, Units: 10},
+		// Sells the first lot held over a year and half the second, less a
+		// 15 fee
+		{Date: ISODateToTime("2021-03-01"), Price: 30, Amount: -450, Units: -15, Fee: 15, Rebalance: true},
+	}}
+
+	lots, gains := d.TaxLots()
+
+	if len(lots) != 1 || !lots[0].Date.Equal(ISODateToTime("2020-06-01")) || !approx(lots[0].Units, 5) || !approx(lots[0].Cost, 100) {
+		t.Errorf("lots %+v, want the 5 newest units costing 100", lots)
+	}
+	// 290 of the proceeds for the 10 oldest units costing 100, 145 for the
+	// 5 newer ones costing 100
+	if !approx(gains.LongTerm, 190) || !approx(gains.ShortTerm, 45) {
+		t.Errorf("gains %+v, want 190 long-term and 45 short-term", gains)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// WindowSource fetches the trading data between From and To once from
+// Source and serves any range within it, so that runs over many overlapping
+// ranges don't fetch the same data over and over.
+type WindowSource struct {
+	Source DataSource
+	From   string
+	To     string
+}
+
+func (ws *WindowSource) HistoricalData(symbol, fromDate, toDate string) (*NASDAQHistoricalAPIResponse, error) {
+	nd, err := ws.Source.HistoricalData(symbol, ws.From, ws.To)
+	if err != nil {
+		return nil, err
+	}
+
+	window := *nd
+	window.Data.TradesTable.Rows = RowsBetween(nd.Data.TradesTable.Rows, ISODateToTime(fromDate), ISODateToTime(toDate))
+	window.Data.TotalRecords = int64(len(window.Data.TradesTable.Rows))
+
+	return &window, nil
+}
+
+type StartTimingRun struct {
+	Offset int // Months
+	From   time.Time
+	PNL    float64
+	CAGR   float64
+}
+
+type StartTiming struct {
+	Runs   []StartTimingRun
+	MinPNL float64
+	MaxPNL float64
+	AvgPNL float64
+	StdDev float64
+}
+
+// NewStartTiming runs the same portfolio with its start date shifted from
+// -months to +months, one month at a time, to show how sensitive the outcome
+// is to when the DCA started.
+func NewStartTiming(specs []SymbolSpec, fromDate, toDate string, f Frequency, spend float64, opts Options, months int) *StartTiming {
+	from := ISODateToTime(fromDate)
+	to := ISODateToTime(toDate)
+
+	opts.Source = &WindowSource{
+		Source: opts.source(),
+		From:   from.AddDate(0, -months, 0).Format("2006-01-02"),
+		To:     toDate,
+	}
+	opts.Benchmark = ""
+
+	st := &StartTiming{MinPNL: math.Inf(1), MaxPNL: math.Inf(-1)}
+	var pnls []float64
+
+	for offset := -months; offset <= months; offset++ {
+		start := from.AddDate(0, offset, 0)
+		if !start.Before(to) {
+			break
+		}
+
+		dp := NewDCAPortfolio(specs, start.Format("2006-01-02"), toDate, f, spend, opts)
+
+		st.Runs = append(st.Runs, StartTimingRun{Offset: offset, From: dp.From, PNL: dp.PNL, CAGR: dp.CAGR})
+		st.MinPNL = math.Min(st.MinPNL, dp.PNL)
+		st.MaxPNL = math.Max(st.MaxPNL, dp.PNL)
+		st.AvgPNL += dp.PNL
+		pnls = append(pnls, dp.PNL)
+	}
+
+	st.AvgPNL /= float64(len(pnls))
+	if len(pnls) > 1 {
+		st.StdDev = StdDev(pnls)
+	}
+
+	return st
+}
+
+func (st *StartTiming) Print() {
+	printer.Fprintf(output, "Offset   Start        PNL         CAGR\n")
+	for _, r := range st.Runs {
+		printer.Fprintf(output, "%+4d m   %s   %8.02f %%   %6.02f %%\n", r.Offset, r.From.Format("2006-01-02"), r.PNL, r.CAGR)
+	}
+	printer.Fprintf(output, "\n")
+	printer.Fprintf(output, "Runs           : %d\n", len(st.Runs))
+	printer.Fprintf(output, "Min PNL        : %.02f %%\n", st.MinPNL)
+	printer.Fprintf(output, "Max PNL        : %.02f %%\n", st.MaxPNL)
+	printer.Fprintf(output, "Avg PNL        : %.02f %%\n", st.AvgPNL)
+	printer.Fprintf(output, "PNL Std Dev    : %.02f %%\n\n", st.StdDev)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStartTimingRunsEveryOffset(t *testing.T) {
+	source := testSource{"X": weekdayRows("2020-01-01", "2020-12-31", func(t time.Time) float64 { return 10 + float64(t.YearDay())/10 })}
+	specs := []SymbolSpec{{Symbol: "X"}}
+
+	st := NewStartTiming(specs, "2020-06-01", "2020-12-31", Monthly, 100, Options{Source: source}, 3)
+	if len(st.Runs) != 7 {
+		t.Fatalf("%d runs, want one for each offset from -3 to +3 months", len(st.Runs))
+	}
+	for i, r := range st.Runs {
+		if r.Offset != i-3 {
+			t.Errorf("run %d offset %d months, want %d", i, r.Offset, i-3)
+		}
+	}
+	if st.MinPNL > st.AvgPNL || st.AvgPNL > st.MaxPNL {
+		t.Errorf("PNL from %v to %v averaging %v", st.MinPNL, st.MaxPNL, st.AvgPNL)
+	}
+
+	// Offsets starting on or after the end date aren't run
+	st = NewStartTiming(specs, "2020-06-01", "2020-07-15", Monthly, 100, Options{Source: source}, 3)
+	if len(st.Runs) != 5 {
+		t.Errorf("%d runs ending on 2020-07-15, want 5 from -3 to +1 months", len(st.Runs))
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"time"
+)
+
+// Gaps between consecutive trading days longer than this are reported.
+// Regular weekends and long holiday weekends stay well below it.
+const maxTradingGap = 7 * 24 * time.Hour
+
+// Differences between the totalRecords NASDAQ reports and the rows it
+// returned larger than this share of the rows are reported, as the data is
+// likely truncated.
+const maxRecordsMismatch = 0.01
+
+type ValidationIssue struct {
+	Row     int
+	Date    string
+	Problem string
+	Corrupt bool
+}
+
+func (vi ValidationIssue) String() string {
+	level := "warning"
+	if vi.Corrupt {
+		level = "error"
+	}
+	if vi.Row < 0 {
+		return fmt.Sprintf("%s: %s", level, vi.Problem)
+	}
+	return fmt.Sprintf("%s: row %d (%s): %s", level, vi.Row, vi.Date, vi.Problem)
+}
+
+// ValidateCacheFile checks that a cached NASDAQ JSON file parses, has rows,
+// lists its rows newest first without duplicates and contains only positive
+// prices. Unusually large gaps between trading days and a totalRecords not
+// matching the rows are reported as warnings.
+func ValidateCacheFile(file string) (issues []ValidationIssue, corrupt bool) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return []ValidationIssue{{Row: -1, Problem: err.Error(), Corrupt: true}}, true
+	}
+
+	ndr := new(NASDAQHistoricalAPIResponse)
+	err = json.Unmarshal(data, ndr)
+	if err != nil {
+		return []ValidationIssue{{Row: -1, Problem: "could not parse JSON: " + err.Error(), Corrupt: true}}, true
+	}
+
+	issues = ValidateHistoricalData(ndr)
+	for _, vi := range issues {
+		if vi.Corrupt {
+			corrupt = true
+		}
+	}
+
+	return issues, corrupt
+}
+
+func ValidateHistoricalData(ndr *NASDAQHistoricalAPIResponse) (issues []ValidationIssue) {
+	rows := ndr.Data.TradesTable.Rows
+	if len(rows) == 0 {
+		return []ValidationIssue{{Row: -1, Problem: "no trading data rows", Corrupt: true}}
+	}
+
+	if total := ndr.Data.TotalRecords; total > 0 {
+		diff := math.Abs(float64(total) - float64(len(rows)))
+		if diff > maxRecordsMismatch*float64(len(rows)) {
+			issues = append(issues, ValidationIssue{Row: -1, Problem: fmt.Sprintf("%d rows but totalRecords is %d, the data may be truncated", len(rows), total)})
+		}
+	}
+
+	var prev time.Time
+
+	for i, r := range rows {
+		t, err := time.Parse(nasdaqDateLayout, r.Date)
+		if err != nil {
+			issues = append(issues, ValidationIssue{Row: i, Date: r.Date, Problem: "unparsable date", Corrupt: true})
+			continue
+		}
+
+		for _, field := range []struct{ name, value string }{
+			{"open", r.Open},
+			{"close", r.Close},
+			{"high", r.High},
+			{"low", r.Low},
+		} {
+			v, err := ParseUSD(field.value)
+			if err == ErrMissingValue {
+				issues = append(issues, ValidationIssue{Row: i, Date: r.Date, Problem: fmt.Sprintf("missing %s price", field.name)})
+			} else if err != nil {
+				issues = append(issues, ValidationIssue{Row: i, Date: r.Date, Problem: fmt.Sprintf("unparsable %s price '%s'", field.name, field.value), Corrupt: true})
+			} else if v <= 0 {
+				issues = append(issues, ValidationIssue{Row: i, Date: r.Date, Problem: fmt.Sprintf("non-positive %s price %s", field.name, field.value), Corrupt: true})
+			}
+		}
+
+		if !prev.IsZero() {
+			if !t.Before(prev) {
+				issues = append(issues, ValidationIssue{Row: i, Date: r.Date, Problem: fmt.Sprintf("out of order, expected a date before %s", prev.Format("2006-01-02")), Corrupt: true})
+			} else if gap := prev.Sub(t); gap > maxTradingGap {
+				issues = append(issues, ValidationIssue{Row: i, Date: r.Date, Problem: fmt.Sprintf("%d day gap before %s", int(gap.Hours()/24), prev.Format("2006-01-02"))})
+			}
+		}
+
+		prev = t
+	}
+
+	return issues
+}
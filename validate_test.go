@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateCorruptCacheFile(t *testing.T) {
+	issues, corrupt := ValidateCacheFile(filepath.Join("testdata", "corrupt.json"))
+	if !corrupt {
+		t.Error("not reported as corrupt")
+	}
+
+	var got []string
+	for _, vi := range issues {
+		got = append(got, vi.String())
+	}
+	want := []string{
+		"warning: 8 rows but totalRecords is 40, the data may be truncated",
+		"error: row 2 (01/09/2020): out of order, expected a date before 2020-01-09",
+		"error: row 3 (01/08/2020): unparsable open price 'abc'",
+		"error: row 4 (2020-01-07): unparsable date",
+		"error: row 5 (01/06/2020): non-positive close price $0.00",
+		"warning: row 6 (01/03/2020): missing high price",
+		"warning: row 7 (12/20/2019): 14 day gap before 2020-01-03",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("issues:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestValidateUnparsableCacheFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "truncated.json")
+	writeFile(t, file, `{"Data": {"Symbol": "AAPL", "tradesTable": {"Rows": [`)
+
+	issues, corrupt := ValidateCacheFile(file)
+	if !corrupt || len(issues) != 1 || !strings.Contains(issues[0].Problem, "could not parse JSON") {
+		t.Errorf("issues %v, corrupt %v, want a single JSON error", issues, corrupt)
+	}
+}
+
+func TestValidateValidCacheFile(t *testing.T) {
+	ndr, _ := testSource{"X": weekdayRows("2020-01-01", "2020-01-31", flatPrice(10))}.HistoricalData("X", "2020-01-01", "2020-01-31")
+
+	if issues := ValidateHistoricalData(ndr); len(issues) != 0 {
+		t.Errorf("issues %v for valid data", issues)
+	}
+}
+
+func TestValidateTotalRecordsMismatch(t *testing.T) {
+	ndr, _ := testSource{"X": weekdayRows("2020-01-01", "2020-12-31", flatPrice(10))}.HistoricalData("X", "2020-01-01", "2020-12-31")
+	rows := len(ndr.Data.TradesTable.Rows)
+
+	// Off by one of the 262 rows is within the tolerance
+	ndr.Data.TotalRecords = int64(rows + 1)
+	if issues := ValidateHistoricalData(ndr); len(issues) != 0 {
+		t.Errorf("issues %v for a totalRecords off by one", issues)
+	}
+
+	ndr.Data.TotalRecords = int64(rows * 2)
+	issues := ValidateHistoricalData(ndr)
+	want := fmt.Sprintf("warning: %d rows but totalRecords is %d, the data may be truncated", rows, rows*2)
+	if len(issues) != 1 || issues[0].String() != want || issues[0].Corrupt {
+		t.Errorf("issues %v, want a single %q", issues, want)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	EqualWeighting      = "equal"
+	VolatilityWeighting = "volatility"
+	MarketCapWeighting  = "marketcap"
+	CustomWeighting     = "custom"
+)
+
+// Weights may be off from summing up to 1 by this much, e.g. from rounding.
+const weightsTolerance = 1e-6
+
+// Weights returns the share of the spend to invest in each symbol, summing
+// up to 1.
+func Weights(symbols []string, fromDate, toDate string, opts Options) ([]float64, error) {
+	switch opts.Weighting {
+	case "", EqualWeighting:
+		weights := make([]float64, len(symbols))
+		for i := range weights {
+			weights[i] = 1 / float64(len(symbols))
+		}
+		return weights, nil
+
+	case VolatilityWeighting:
+		return volatilityWeights(symbols, fromDate, toDate, opts)
+
+	case MarketCapWeighting:
+		return marketCapWeights(symbols, opts.MarketCaps)
+
+	case CustomWeighting:
+		return customWeights(symbols, opts.CustomWeights, opts.NormalizeWeights)
+	}
+
+	return nil, fmt.Errorf("unknown weighting '%s'", opts.Weighting)
+}
+
+// volatilityWeights weights each symbol inversely to its volatility over the
+// period so that each position contributes roughly the same risk.
+func volatilityWeights(symbols []string, fromDate, toDate string, opts Options) ([]float64, error) {
+	weights := make([]float64, len(symbols))
+	var sum float64
+
+	for i, symbol := range symbols {
+		nd, err := opts.source().HistoricalData(symbol, fromDate, toDate)
+		if err != nil {
+			return nil, err
+		}
+
+		vol := nd.WithoutMissingPrices().Volatility()
+		if vol == 0 || math.IsNaN(vol) || math.IsInf(vol, 0) {
+			return nil, fmt.Errorf("cannot weight %s by volatility, its price doesn't move", symbol)
+		}
+
+		weights[i] = 1 / vol
+		sum += weights[i]
+	}
+
+	for i := range weights {
+		weights[i] /= sum
+	}
+
+	return weights, nil
+}
+
+func marketCapWeights(symbols []string, marketCaps map[string]float64) ([]float64, error) {
+	weights := make([]float64, len(symbols))
+	var sum float64
+
+	for i, symbol := range symbols {
+		mc, ok := marketCaps[strings.ToUpper(symbol)]
+		if !ok {
+			return nil, fmt.Errorf("no market cap given for %s", symbol)
+		}
+		weights[i] = mc
+		sum += mc
+	}
+
+	for i := range weights {
+		weights[i] /= sum
+	}
+
+	return weights, nil
+}
+
+// customWeights checks that the weights given match the symbols and sum up
+// to 1, or rescales them to with normalize set.
+func customWeights(symbols []string, given []float64, normalize bool) ([]float64, error) {
+	if len(given) != len(symbols) {
+		return nil, fmt.Errorf("got %d weights for %d symbols", len(given), len(symbols))
+	}
+
+	var sum float64
+	for i, w := range given {
+		if w < 0 {
+			return nil, fmt.Errorf("weight %g for %s is negative", w, symbols[i])
+		}
+		sum += w
+	}
+	if sum == 0 {
+		return nil, fmt.Errorf("weights are all zero")
+	}
+
+	if !normalize {
+		if math.Abs(sum-1) > weightsTolerance {
+			return nil, fmt.Errorf("weights sum up to %g instead of 1, use --weights-normalize to rescale them", sum)
+		}
+		return given, nil
+	}
+
+	weights := make([]float64, len(given))
+	for i, w := range given {
+		weights[i] = w / sum
+	}
+
+	return weights, nil
+}
+
+// LoadMarketCaps reads a CSV file with two columns, a symbol and its market
+// capitalization, e.g. "AAPL,3400000000000". An optional header row is
+// skipped.
+func LoadMarketCaps(file string) (map[string]float64, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cr := csv.NewReader(f)
+	cr.FieldsPerRecord = 2
+	cr.TrimLeadingSpace = true
+	cr.Comment = '#'
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not read market cap file %s: %w", file, err)
+	}
+
+	marketCaps := make(map[string]float64)
+
+	for i, rec := range records {
+		mc, err := strconv.ParseFloat(strings.NewReplacer("$", "", ",", "").Replace(rec[1]), 64)
+		if err != nil {
+			if i == 0 {
+				continue // Header
+			}
+			return nil, fmt.Errorf("market cap file %s: invalid market cap '%s' for %s", file, rec[1], rec[0])
+		}
+		if mc <= 0 {
+			return nil, fmt.Errorf("market cap file %s: market cap for %s must be positive", file, rec[0])
+		}
+		marketCaps[strings.ToUpper(strings.TrimSpace(rec[0]))] = mc
+	}
+
+	return marketCaps, nil
+}
+
+// Volatility returns the standard deviation of the daily returns. Days
+// without a positive price are skipped, the return over them taken from the
+// days around them, or NaN is returned if there are fewer than 2 returns.
+func (ndr *NASDAQHistoricalAPIResponse) Volatility() float64 {
+	var returns []float64
+	var newer float64
+	for _, r := range ndr.Data.TradesTable.Rows {
+		p := r.AvgPrice()
+		if p <= 0 {
+			continue
+		}
+		if newer > 0 {
+			// Rows are newest first
+			returns = append(returns, newer/p-1)
+		}
+		newer = p
+	}
+	if len(returns) < 2 {
+		return math.NaN()
+	}
+
+	return StdDev(returns)
+}
+
+// StdDev returns the sample standard deviation of values.
+func StdDev(values []float64) float64 {
+	var mean float64
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+
+	var variance float64
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	variance /= float64(len(values) - 1)
+
+	return math.Sqrt(variance)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// alternatingPrice returns a price function for weekdayRows moving between
+// 100 and 100 + swing every other day.
+func alternatingPrice(swing float64) func(time.Time) float64 {
+	return func(t time.Time) float64 {
+		if t.YearDay()%2 == 0 {
+			return 100 + swing
+		}
+		return 100
+	}
+}
+
+func TestVolatilityWeighting(t *testing.T) {
+	source := testSource{
+		"LOW":  weekdayRows("2020-01-01", "2020-03-31", alternatingPrice(1)),
+		"HIGH": weekdayRows("2020-01-01", "2020-03-31", alternatingPrice(3)),
+	}
+
+	weights, err := Weights([]string{"HIGH", "LOW"}, "2020-01-01", "2020-03-31", Options{Source: source, Weighting: VolatilityWeighting})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if weights[1] <= weights[0] {
+		t.Errorf("weights %v, want the lower volatility LOW weighted higher", weights)
+	}
+	// Three times the moves, a third of the weight
+	if math.Abs(weights[1]-0.75) > 0.01 || math.Abs(weights[0]+weights[1]-1) > weightsTolerance {
+		t.Errorf("weights %v, want about 0.25 and 0.75", weights)
+	}
+}
+
+func TestVolatilityWeightingFailsOnFlatPrices(t *testing.T) {
+	source := testSource{"FLAT": weekdayRows("2020-01-01", "2020-03-31", flatPrice(10))}
+
+	if _, err := Weights([]string{"FLAT"}, "2020-01-01", "2020-03-31", Options{Source: source, Weighting: VolatilityWeighting}); err == nil {
+		t.Error("no error weighting a flat price by volatility")
+	}
+}
+
+func TestVolatilityWeightingSkipsZeroPrices(t *testing.T) {
+	// A zero price on a day between two at 101
+	source := testSource{
+		"LOW": weekdayRows("2020-01-01", "2020-03-31", func(t time.Time) float64 {
+			if t.Format("2006-01-02") == "2020-02-04" {
+				return 0
+			}
+			return alternatingPrice(1)(t)
+		}),
+		"HIGH": weekdayRows("2020-01-01", "2020-03-31", alternatingPrice(3)),
+	}
+
+	weights, err := Weights([]string{"HIGH", "LOW"}, "2020-01-01", "2020-03-31", Options{Source: source, Weighting: VolatilityWeighting})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.IsNaN(weights[0]) || math.Abs(weights[1]-0.75) > 0.02 || math.Abs(weights[0]+weights[1]-1) > weightsTolerance {
+		t.Errorf("weights %v, want about 0.25 and 0.75 as without the zero price", weights)
+	}
+}
+
+func TestMarketCapWeighting(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "marketcaps.csv")
+	writeFile(t, file, "symbol,market_cap\naapl,\"$3,000,000,000,000\"\nMSFT,2000000000000\nSMALL,1000000000000\n")
+
+	marketCaps, err := LoadMarketCaps(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	weights, err := Weights([]string{"AAPL", "msft", "SMALL"}, "2020-01-01", "2020-12-31", Options{Weighting: MarketCapWeighting, MarketCaps: marketCaps})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []float64{0.5, 1.0 / 3, 1.0 / 6} {
+		if math.Abs(weights[i]-want) > 1e-9 {
+			t.Errorf("weights %v, want 1/2, 1/3 and 1/6", weights)
+			break
+		}
+	}
+
+	if _, err := Weights([]string{"AAPL", "TSLA"}, "2020-01-01", "2020-12-31", Options{Weighting: MarketCapWeighting, MarketCaps: marketCaps}); err == nil {
+		t.Error("no error for a symbol without a market cap")
+	}
+}
+
+func TestNormalizeCustomWeights(t *testing.T) {
+	symbols := []string{"A", "B", "C"}
+	weigh := func(given []float64, normalize bool) ([]float64, error) {
+		return Weights(symbols, "2020-01-01", "2020-12-31", Options{Weighting: CustomWeighting, CustomWeights: given, NormalizeWeights: normalize})
+	}
+
+	if _, err := weigh([]float64{0.33, 0.33, 0.33}, false); err == nil {
+		t.Error("no error for weights summing to 0.99 without normalizing")
+	}
+
+	weights, err := weigh([]float64{0.2, 0.2, 0.1}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []float64{0.4, 0.4, 0.2} {
+		if math.Abs(weights[i]-want) > 1e-12 {
+			t.Errorf("weights %v, want 0.4, 0.4 and 0.2 keeping their proportions", weights)
+			break
+		}
+	}
+
+	weights, err = weigh([]float64{0.33, 0.33, 0.33}, true)
+	if err != nil || math.Abs(weights[0]+weights[1]+weights[2]-1) > 1e-12 {
+		t.Errorf("weights %v, %v, want them summing to 1", weights, err)
+	}
+}